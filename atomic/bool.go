@@ -4,6 +4,16 @@ import "sync/atomic"
 
 type BoolAtomic uint32
 
+// Bool 和 BoolAtomic 相同，与其他原子类型的命名保持一致
+type Bool = BoolAtomic
+
+func boolToUint32(v bool) uint32 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
 func (b *BoolAtomic) Get() bool {
 	return atomic.LoadUint32((*uint32)(b)) != 0
 }
@@ -15,3 +25,33 @@ func (b *BoolAtomic) Set(v bool) {
 		atomic.StoreUint32((*uint32)(b), 0)
 	}
 }
+
+// Load 和 Get 相同
+func (b *BoolAtomic) Load() bool {
+	return b.Get()
+}
+
+// Store 和 Set 相同
+func (b *BoolAtomic) Store(v bool) {
+	b.Set(v)
+}
+
+// Swap 设置新值并返回旧值
+func (b *BoolAtomic) Swap(v bool) bool {
+	return atomic.SwapUint32((*uint32)(b), boolToUint32(v)) != 0
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (b *BoolAtomic) CompareAndSwap(old, new bool) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(b), boolToUint32(old), boolToUint32(new))
+}
+
+// Toggle 取反并返回旧值
+func (b *BoolAtomic) Toggle() bool {
+	for {
+		old := b.Get()
+		if b.CompareAndSwap(old, !old) {
+			return old
+		}
+	}
+}
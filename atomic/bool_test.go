@@ -0,0 +1,46 @@
+package atomic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBool(t *testing.T) {
+	var b Bool
+	if b.Load() {
+		t.Fatal("zero value should be false")
+	}
+	b.Store(true)
+	if !b.Get() {
+		t.Fatal("Get after Store(true) = false")
+	}
+	if old := b.Swap(false); !old || b.Load() {
+		t.Fatalf("Swap(false) = %v, Load = %v", old, b.Load())
+	}
+	if b.CompareAndSwap(true, false) {
+		t.Fatal("CompareAndSwap(true, false) succeeded on false")
+	}
+	if !b.CompareAndSwap(false, true) || !b.Load() {
+		t.Fatal("CompareAndSwap(false, true) failed")
+	}
+	if old := b.Toggle(); !old || b.Load() {
+		t.Fatalf("Toggle = %v, Load = %v", old, b.Load())
+	}
+}
+
+func TestBoolToggleConcurrent(t *testing.T) {
+	var b Bool
+	var wg sync.WaitGroup
+	// 偶数次取反之后回到初始值
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Toggle()
+		}()
+	}
+	wg.Wait()
+	if b.Load() {
+		t.Fatal("100 toggles should leave the value false")
+	}
+}
@@ -0,0 +1,33 @@
+package atomic
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Duration 原子操作的 time.Duration
+// 在32位平台上作为结构体字段时需要放在首位，保证64位对齐
+type Duration int64
+
+func (d *Duration) Load() time.Duration {
+	return time.Duration(atomic.LoadInt64((*int64)(d)))
+}
+
+func (d *Duration) Store(v time.Duration) {
+	atomic.StoreInt64((*int64)(d), int64(v))
+}
+
+// Swap 设置新值并返回旧值
+func (d *Duration) Swap(v time.Duration) time.Duration {
+	return time.Duration(atomic.SwapInt64((*int64)(d), int64(v)))
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (d *Duration) CompareAndSwap(old, new time.Duration) bool {
+	return atomic.CompareAndSwapInt64((*int64)(d), int64(old), int64(new))
+}
+
+// Add 加上 delta 并返回新值
+func (d *Duration) Add(delta time.Duration) time.Duration {
+	return time.Duration(atomic.AddInt64((*int64)(d), int64(delta)))
+}
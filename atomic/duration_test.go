@@ -0,0 +1,20 @@
+package atomic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	var d Duration
+	d.Store(time.Second)
+	if v := d.Add(time.Millisecond); v != time.Second+time.Millisecond {
+		t.Fatalf("Add = %v", v)
+	}
+	if old := d.Swap(time.Minute); old != time.Second+time.Millisecond {
+		t.Fatalf("Swap = %v", old)
+	}
+	if !d.CompareAndSwap(time.Minute, time.Hour) || d.Load() != time.Hour {
+		t.Fatalf("CompareAndSwap failed, Load = %v", d.Load())
+	}
+}
@@ -0,0 +1,40 @@
+package atomic
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Float64 原子操作的 float64，内部以 uint64 保存位模式
+// 在32位平台上作为结构体字段时需要放在首位，保证64位对齐
+type Float64 uint64
+
+func (f *Float64) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64((*uint64)(f)))
+}
+
+func (f *Float64) Store(v float64) {
+	atomic.StoreUint64((*uint64)(f), math.Float64bits(v))
+}
+
+// Swap 设置新值并返回旧值
+func (f *Float64) Swap(v float64) float64 {
+	return math.Float64frombits(atomic.SwapUint64((*uint64)(f), math.Float64bits(v)))
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+// 按位比较，所以 NaN 也可以比较，+0 和 -0 被认为不相等
+func (f *Float64) CompareAndSwap(old, new float64) bool {
+	return atomic.CompareAndSwapUint64((*uint64)(f), math.Float64bits(old), math.Float64bits(new))
+}
+
+// Add 加上 delta 并返回新值
+func (f *Float64) Add(delta float64) float64 {
+	for {
+		old := f.Load()
+		v := old + delta
+		if f.CompareAndSwap(old, v) {
+			return v
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package atomic
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestFloat64(t *testing.T) {
+	var f Float64
+	if f.Load() != 0 {
+		t.Fatal("zero value should be 0")
+	}
+	f.Store(1.5)
+	if old := f.Swap(2.5); old != 1.5 {
+		t.Fatalf("Swap = %v, want 1.5", old)
+	}
+	if f.CompareAndSwap(1.5, 3) || !f.CompareAndSwap(2.5, 3) {
+		t.Fatal("CompareAndSwap compared the wrong value")
+	}
+	// 按位比较，NaN 可以和自己比较成功
+	f.Store(math.NaN())
+	if !f.CompareAndSwap(math.NaN(), 1) {
+		t.Fatal("CompareAndSwap(NaN, 1) failed")
+	}
+}
+
+func TestFloat64AddConcurrent(t *testing.T) {
+	var f Float64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				f.Add(0.5)
+			}
+		}()
+	}
+	wg.Wait()
+	if f.Load() != 4000 {
+		t.Fatalf("Load = %v, want 4000", f.Load())
+	}
+}
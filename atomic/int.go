@@ -0,0 +1,83 @@
+package atomic
+
+import "sync/atomic"
+
+// Int32 原子操作的 int32
+type Int32 int32
+
+func (i *Int32) Load() int32 {
+	return atomic.LoadInt32((*int32)(i))
+}
+
+func (i *Int32) Store(v int32) {
+	atomic.StoreInt32((*int32)(i), v)
+}
+
+// Swap 设置新值并返回旧值
+func (i *Int32) Swap(v int32) int32 {
+	return atomic.SwapInt32((*int32)(i), v)
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (i *Int32) CompareAndSwap(old, new int32) bool {
+	return atomic.CompareAndSwapInt32((*int32)(i), old, new)
+}
+
+// Add 加上 delta 并返回新值
+func (i *Int32) Add(delta int32) int32 {
+	return atomic.AddInt32((*int32)(i), delta)
+}
+
+// Int64 原子操作的 int64
+// 在32位平台上作为结构体字段时需要放在首位，保证64位对齐
+type Int64 int64
+
+func (i *Int64) Load() int64 {
+	return atomic.LoadInt64((*int64)(i))
+}
+
+func (i *Int64) Store(v int64) {
+	atomic.StoreInt64((*int64)(i), v)
+}
+
+// Swap 设置新值并返回旧值
+func (i *Int64) Swap(v int64) int64 {
+	return atomic.SwapInt64((*int64)(i), v)
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (i *Int64) CompareAndSwap(old, new int64) bool {
+	return atomic.CompareAndSwapInt64((*int64)(i), old, new)
+}
+
+// Add 加上 delta 并返回新值
+func (i *Int64) Add(delta int64) int64 {
+	return atomic.AddInt64((*int64)(i), delta)
+}
+
+// Uint64 原子操作的 uint64
+// 在32位平台上作为结构体字段时需要放在首位，保证64位对齐
+type Uint64 uint64
+
+func (u *Uint64) Load() uint64 {
+	return atomic.LoadUint64((*uint64)(u))
+}
+
+func (u *Uint64) Store(v uint64) {
+	atomic.StoreUint64((*uint64)(u), v)
+}
+
+// Swap 设置新值并返回旧值
+func (u *Uint64) Swap(v uint64) uint64 {
+	return atomic.SwapUint64((*uint64)(u), v)
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (u *Uint64) CompareAndSwap(old, new uint64) bool {
+	return atomic.CompareAndSwapUint64((*uint64)(u), old, new)
+}
+
+// Add 加上 delta 并返回新值
+func (u *Uint64) Add(delta uint64) uint64 {
+	return atomic.AddUint64((*uint64)(u), delta)
+}
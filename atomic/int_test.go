@@ -0,0 +1,67 @@
+package atomic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInt32(t *testing.T) {
+	var i Int32
+	i.Store(5)
+	if old := i.Swap(7); old != 5 {
+		t.Fatalf("Swap = %d, want 5", old)
+	}
+	if i.CompareAndSwap(5, 9) || !i.CompareAndSwap(7, 9) {
+		t.Fatal("CompareAndSwap compared the wrong value")
+	}
+	if v := i.Add(-10); v != -1 || i.Load() != -1 {
+		t.Fatalf("Add = %d, Load = %d, want -1", v, i.Load())
+	}
+}
+
+func TestInt64(t *testing.T) {
+	var i Int64
+	i.Store(1 << 40)
+	if old := i.Swap(3); old != 1<<40 {
+		t.Fatalf("Swap = %d, want %d", old, int64(1<<40))
+	}
+	if !i.CompareAndSwap(3, 4) || i.Load() != 4 {
+		t.Fatal("CompareAndSwap(3, 4) failed")
+	}
+}
+
+func TestUint64(t *testing.T) {
+	var u Uint64
+	u.Store(1)
+	if v := u.Add(^uint64(0)); v != 0 {
+		t.Fatalf("Add(-1) = %d, want 0", v)
+	}
+	if old := u.Swap(8); old != 0 || u.Load() != 8 {
+		t.Fatalf("Swap = %d, Load = %d", old, u.Load())
+	}
+	if !u.CompareAndSwap(8, 9) {
+		t.Fatal("CompareAndSwap(8, 9) failed")
+	}
+}
+
+func TestIntAddConcurrent(t *testing.T) {
+	var i32 Int32
+	var i64 Int64
+	var u64 Uint64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				i32.Add(1)
+				i64.Add(1)
+				u64.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if i32.Load() != 8000 || i64.Load() != 8000 || u64.Load() != 8000 {
+		t.Fatalf("got %d %d %d, want 8000", i32.Load(), i64.Load(), u64.Load())
+	}
+}
@@ -0,0 +1,89 @@
+package atomic
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// box 保证 atomic.Value 中保存的始终是同一种类型
+// 即使 T 是接口类型，保存不同的具体类型也不会 panic
+type box[T any] struct {
+	v T
+}
+
+// Value 类型安全的 atomic.Value，未设置时 Load 返回零值
+type Value[T any] struct {
+	v atomic.Value
+}
+
+func (v *Value[T]) Load() T {
+	b, ok := v.v.Load().(box[T])
+	if !ok {
+		var zero T
+		return zero
+	}
+	return b.v
+}
+
+func (v *Value[T]) Store(val T) {
+	v.v.Store(box[T]{v: val})
+}
+
+// Swap 设置新值并返回旧值
+func (v *Value[T]) Swap(val T) T {
+	b, _ := v.v.Swap(box[T]{v: val}).(box[T])
+	return b.v
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+// T 不可比较时会 panic，未设置过的 Value 当作零值比较
+func (v *Value[T]) CompareAndSwap(old, new T) bool {
+	if v.v.CompareAndSwap(box[T]{v: old}, box[T]{v: new}) {
+		return true
+	}
+	// 还没有设置过值，只有 old 为零值时才能设置
+	var zero T
+	return any(old) == any(zero) && v.v.CompareAndSwap(nil, box[T]{v: new})
+}
+
+// String 原子操作的 string
+type String struct {
+	v Value[string]
+}
+
+func (s *String) Load() string {
+	return s.v.Load()
+}
+
+func (s *String) Store(v string) {
+	s.v.Store(v)
+}
+
+// Swap 设置新值并返回旧值
+func (s *String) Swap(v string) string {
+	return s.v.Swap(v)
+}
+
+// CompareAndSwap 当前值等于 old 时设置为 new，返回是否设置成功
+func (s *String) CompareAndSwap(old, new string) bool {
+	return s.v.CompareAndSwap(old, new)
+}
+
+// Time 原子操作的 time.Time
+// time.Time 用 == 比较没有意义，所以不提供 CompareAndSwap
+type Time struct {
+	v Value[time.Time]
+}
+
+func (t *Time) Load() time.Time {
+	return t.v.Load()
+}
+
+func (t *Time) Store(v time.Time) {
+	t.v.Store(v)
+}
+
+// Swap 设置新值并返回旧值
+func (t *Time) Swap(v time.Time) time.Time {
+	return t.v.Swap(v)
+}
@@ -0,0 +1,64 @@
+package atomic
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	var v Value[int]
+	if v.Load() != 0 {
+		t.Fatal("unset Value should load the zero value")
+	}
+	if v.CompareAndSwap(1, 2) {
+		t.Fatal("CompareAndSwap(1, 2) succeeded on unset Value")
+	}
+	if !v.CompareAndSwap(0, 2) || v.Load() != 2 {
+		t.Fatal("CompareAndSwap(0, 2) failed on unset Value")
+	}
+	if old := v.Swap(3); old != 2 {
+		t.Fatalf("Swap = %d, want 2", old)
+	}
+}
+
+func TestValueInterface(t *testing.T) {
+	// 接口类型的 Value 可以保存不同的具体类型
+	var v Value[error]
+	v.Store(io.EOF)
+	v.Store(errors.New("other"))
+	if v.Load() == io.EOF {
+		t.Fatal("Store did not replace the value")
+	}
+	v.Store(nil)
+	if v.Load() != nil {
+		t.Fatal("Store(nil) did not clear the value")
+	}
+}
+
+func TestString(t *testing.T) {
+	var s String
+	if s.Load() != "" {
+		t.Fatal("zero value should be empty")
+	}
+	s.Store("a")
+	if old := s.Swap("b"); old != "a" {
+		t.Fatalf("Swap = %q", old)
+	}
+	if !s.CompareAndSwap("b", "c") || s.Load() != "c" {
+		t.Fatal("CompareAndSwap failed")
+	}
+}
+
+func TestTime(t *testing.T) {
+	var tm Time
+	if !tm.Load().IsZero() {
+		t.Fatal("zero value should be the zero time")
+	}
+	now := time.Now()
+	tm.Store(now)
+	if old := tm.Swap(now.Add(time.Second)); !old.Equal(now) {
+		t.Fatalf("Swap = %v, want %v", old, now)
+	}
+}
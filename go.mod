@@ -1,6 +1,6 @@
 module github.com/limitzhang87/go-utils
 
-go 1.17
+go 1.18
 
 require (
 	github.com/hpcloud/tail v1.0.0 // indirect
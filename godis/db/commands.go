@@ -0,0 +1,119 @@
+package db
+
+import (
+	"godis/redis/reply"
+)
+
+func init() {
+	RegisterCommand("ping", execPing, -1)
+	RegisterCommand("echo", execEcho, 2)
+	RegisterCommand("get", execGet, 2)
+	RegisterCommand("set", execSet, 3)
+	RegisterCommand("del", execDel, -2)
+	RegisterCommand("exists", execExists, -2)
+	RegisterCommand("mset", execMSet, -3)
+	RegisterCommand("mget", execMGet, -2)
+	RegisterCommand("rename", execRename, 3)
+}
+
+// PING [message]
+func execPing(db *StandaloneDB, args [][]byte) reply.Reply {
+	switch len(args) {
+	case 0:
+		return reply.MakePongReply()
+	case 1:
+		return reply.MakeBulkReply(args[0])
+	default:
+		return reply.MakeArgNumErrReply("ping")
+	}
+}
+
+// ECHO message
+func execEcho(db *StandaloneDB, args [][]byte) reply.Reply {
+	return reply.MakeBulkReply(args[0])
+}
+
+// GET key
+func execGet(db *StandaloneDB, args [][]byte) reply.Reply {
+	val, ok := db.data.Get(string(args[0]))
+	if !ok {
+		return reply.MakeNullBulkReply()
+	}
+	return reply.MakeBulkReply(val.([]byte))
+}
+
+// SET key value
+func execSet(db *StandaloneDB, args [][]byte) reply.Reply {
+	db.data.Put(string(args[0]), args[1])
+	return reply.MakeOkReply()
+}
+
+// DEL key [key ...]
+func execDel(db *StandaloneDB, args [][]byte) reply.Reply {
+	var deleted int64
+	for _, key := range args {
+		deleted += int64(db.data.Remove(string(key)))
+	}
+	return reply.MakeIntReply(deleted)
+}
+
+// EXISTS key [key ...]
+func execExists(db *StandaloneDB, args [][]byte) reply.Reply {
+	var count int64
+	for _, key := range args {
+		if _, ok := db.data.Get(string(key)); ok {
+			count++
+		}
+	}
+	return reply.MakeIntReply(count)
+}
+
+// MSET key value [key value ...]
+func execMSet(db *StandaloneDB, args [][]byte) reply.Reply {
+	if len(args)%2 != 0 {
+		return reply.MakeArgNumErrReply("mset")
+	}
+	keys := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, string(args[i]))
+	}
+	// 同时锁住所有 key，其他客户端不会看到只写了一部分的结果
+	db.data.RWLocks(keys, nil)
+	defer db.data.RWUnLocks(keys, nil)
+	for i, key := range keys {
+		db.data.PutWithLock(key, args[2*i+1])
+	}
+	return reply.MakeOkReply()
+}
+
+// MGET key [key ...]
+func execMGet(db *StandaloneDB, args [][]byte) reply.Reply {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = string(arg)
+	}
+	db.data.RWLocks(nil, keys)
+	defer db.data.RWUnLocks(nil, keys)
+	result := make([][]byte, len(keys))
+	for i, key := range keys {
+		if val, ok := db.data.GetWithLock(key); ok {
+			result[i] = val.([]byte)
+		}
+	}
+	return reply.MakeMultiBulkReply(result)
+}
+
+// RENAME key newkey
+func execRename(db *StandaloneDB, args [][]byte) reply.Reply {
+	src, dest := string(args[0]), string(args[1])
+	keys := []string{src, dest}
+	db.data.RWLocks(keys, nil)
+	defer db.data.RWUnLocks(keys, nil)
+	val, ok := db.data.GetWithLock(src)
+	if !ok {
+		return reply.MakeErrReply("ERR no such key")
+	}
+	db.data.RemoveWithLock(src)
+	db.data.PutWithLock(dest, val)
+	return reply.MakeOkReply()
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"godis/redis/reply"
+)
+
+func newTestDB() *StandaloneDB {
+	return &StandaloneDB{data: MakeConcurrent(16)}
+}
+
+func exec(db *StandaloneDB, cmd string) string {
+	var args [][]byte
+	for _, arg := range strings.Fields(cmd) {
+		args = append(args, []byte(arg))
+	}
+	return string(db.Exec(args).ToBytes())
+}
+
+func assertExec(t *testing.T, db *StandaloneDB, cmd string, want string) {
+	t.Helper()
+	if got := exec(db, cmd); got != want {
+		t.Fatalf("%s = %q, want %q", cmd, got, want)
+	}
+}
+
+func TestExec(t *testing.T) {
+	db := newTestDB()
+	assertExec(t, db, "PING", "+PONG\r\n")
+	assertExec(t, db, "ping hello", "$5\r\nhello\r\n")
+	assertExec(t, db, "SET k v", "+OK\r\n")
+	assertExec(t, db, "GET k", "$1\r\nv\r\n")
+	assertExec(t, db, "GET missing", "$-1\r\n")
+	assertExec(t, db, "EXISTS k k missing", ":2\r\n")
+	assertExec(t, db, "DEL k missing", ":1\r\n")
+	assertExec(t, db, "GET k v", "-ERR wrong number of arguments for 'get' command\r\n")
+	assertExec(t, db, "MSET k", "-ERR wrong number of arguments for 'mset' command\r\n")
+	assertExec(t, db, "NOPE", "-ERR unknown command 'nope'\r\n")
+}
+
+func TestExecEmptyArgs(t *testing.T) {
+	// 空命令在 handler 中就被忽略了，Exec 不会 panic 到调用方
+	if r := newTestDB().Exec(nil); !reply.IsErrorReply(r) {
+		t.Fatalf("Exec(nil) = %q, want an error reply", r.ToBytes())
+	}
+}
+
+func TestMSetDuplicateKeys(t *testing.T) {
+	db := newTestDB()
+	assertExec(t, db, "MSET k 1 k 2", "+OK\r\n")
+	assertExec(t, db, "GET k", "$1\r\n2\r\n")
+	if n := db.data.Len(); n != 1 {
+		t.Fatalf("Len = %d after MSET with a duplicate key, want 1", n)
+	}
+	assertExec(t, db, "MGET k k missing", "*3\r\n$1\r\n2\r\n$1\r\n2\r\n$-1\r\n")
+}
+
+func TestMSetSameShard(t *testing.T) {
+	db := newTestDB()
+	keys := sameShardKeys(db.data.(*ConcurrentDict), 3)
+	assertExec(t, db, "MSET "+keys[0]+" a "+keys[1]+" b "+keys[2]+" c", "+OK\r\n")
+	assertExec(t, db, "MGET "+strings.Join(keys, " "), "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n")
+	assertUnlocked(t, db.data.(*ConcurrentDict))
+}
+
+func TestRename(t *testing.T) {
+	db := newTestDB()
+	keys := sameShardKeys(db.data.(*ConcurrentDict), 2)
+	src, dest := keys[0], keys[1]
+
+	assertExec(t, db, "RENAME "+src+" "+dest, "-ERR no such key\r\n")
+	assertExec(t, db, "SET "+src+" v", "+OK\r\n")
+	// 两个 key 在同一个分段
+	assertExec(t, db, "RENAME "+src+" "+dest, "+OK\r\n")
+	assertExec(t, db, "GET "+src, "$-1\r\n")
+	assertExec(t, db, "GET "+dest, "$1\r\nv\r\n")
+	// 改成自己的名字
+	assertExec(t, db, "RENAME "+dest+" "+dest, "+OK\r\n")
+	assertExec(t, db, "GET "+dest, "$1\r\nv\r\n")
+	if n := db.data.Len(); n != 1 {
+		t.Fatalf("Len = %d, want 1", n)
+	}
+	assertUnlocked(t, db.data.(*ConcurrentDict))
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/limitzhang87/go-utils/atomic"
+)
+
+// ConcurrentDict 分段加锁的并发安全字典
+// key 使用 FNV 哈希映射到分段，每个分段有自己的读写锁，不同分段的操作互不阻塞
+type ConcurrentDict struct {
+	// 64 位原子操作要求 8 字节对齐，放在第一个字段保证 32 位平台上也对齐
+	count atomic.Int64
+	table []*shard
+}
+
+var _ Dict = (*ConcurrentDict)(nil)
+
+type shard struct {
+	m     map[string]interface{}
+	mutex sync.RWMutex
+}
+
+// computeCapacity 分段数向上取整为 2 的幂，这样可以用位运算代替取模
+func computeCapacity(param int) int {
+	if param <= 16 {
+		return 16
+	}
+	n := param - 1
+	n |= n >> 1
+	n |= n >> 2
+	n |= n >> 4
+	n |= n >> 8
+	n |= n >> 16
+	if n < 0 || n >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return n + 1
+}
+
+// MakeConcurrent 创建字典, shardCount 会向上取整为 2 的幂，最小 16
+func MakeConcurrent(shardCount int) *ConcurrentDict {
+	shardCount = computeCapacity(shardCount)
+	table := make([]*shard, shardCount)
+	for i := range table {
+		table[i] = &shard{m: make(map[string]interface{})}
+	}
+	return &ConcurrentDict{table: table}
+}
+
+const prime32 = uint32(16777619)
+
+// fnv32 FNV-1a 哈希，直接在 string 上计算避免分配
+func fnv32(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+func (dict *ConcurrentDict) spread(key string) uint32 {
+	return fnv32(key) & uint32(len(dict.table)-1)
+}
+
+func (dict *ConcurrentDict) getShard(index uint32) *shard {
+	return dict.table[index]
+}
+
+func (dict *ConcurrentDict) Get(key string) (val interface{}, exists bool) {
+	s := dict.getShard(dict.spread(key))
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	val, exists = s.m[key]
+	return
+}
+
+// GetWithLock 调用方已经通过 RWLocks 锁住了 key 时使用
+func (dict *ConcurrentDict) GetWithLock(key string) (val interface{}, exists bool) {
+	s := dict.getShard(dict.spread(key))
+	val, exists = s.m[key]
+	return
+}
+
+func (dict *ConcurrentDict) Len() int {
+	return int(dict.count.Load())
+}
+
+// Put 写入键值对，返回新增的键数量，覆盖已有的键时返回 0
+func (dict *ConcurrentDict) Put(key string, val interface{}) (result int) {
+	s := dict.getShard(dict.spread(key))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return dict.put(s, key, val)
+}
+
+// PutWithLock 调用方已经通过 RWLocks 写锁住了 key 时使用
+func (dict *ConcurrentDict) PutWithLock(key string, val interface{}) (result int) {
+	return dict.put(dict.getShard(dict.spread(key)), key, val)
+}
+
+func (dict *ConcurrentDict) put(s *shard, key string, val interface{}) int {
+	if _, ok := s.m[key]; ok {
+		s.m[key] = val
+		return 0
+	}
+	s.m[key] = val
+	dict.count.Add(1)
+	return 1
+}
+
+// PutIfAbsent key 不存在时才写入，返回写入的数量
+func (dict *ConcurrentDict) PutIfAbsent(key string, val interface{}) (result int) {
+	s := dict.getShard(dict.spread(key))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.m[key]; ok {
+		return 0
+	}
+	s.m[key] = val
+	dict.count.Add(1)
+	return 1
+}
+
+// PutIfExists key 存在时才写入，返回修改的数量
+func (dict *ConcurrentDict) PutIfExists(key string, val interface{}) (result int) {
+	s := dict.getShard(dict.spread(key))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.m[key]; ok {
+		s.m[key] = val
+		return 1
+	}
+	return 0
+}
+
+// Remove 删除 key，返回删除的数量
+func (dict *ConcurrentDict) Remove(key string) (result int) {
+	s := dict.getShard(dict.spread(key))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return dict.remove(s, key)
+}
+
+// RemoveWithLock 调用方已经通过 RWLocks 写锁住了 key 时使用
+func (dict *ConcurrentDict) RemoveWithLock(key string) (result int) {
+	return dict.remove(dict.getShard(dict.spread(key)), key)
+}
+
+func (dict *ConcurrentDict) remove(s *shard, key string) int {
+	if _, ok := s.m[key]; ok {
+		delete(s.m, key)
+		dict.count.Add(-1)
+		return 1
+	}
+	return 0
+}
+
+// ForEach 遍历所有键值对，遍历期间每个分段依次加读锁
+// consumer 中不能修改字典，否则会死锁
+func (dict *ConcurrentDict) ForEach(consumer Consumer) {
+	for _, s := range dict.table {
+		if !s.forEach(consumer) {
+			return
+		}
+	}
+}
+
+func (s *shard) forEach(consumer Consumer) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for key, val := range s.m {
+		if !consumer(key, val) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys 返回所有的 key，遍历期间的并发修改可能不会体现在结果中
+func (dict *ConcurrentDict) Keys() []string {
+	keys := make([]string, 0, dict.Len())
+	dict.ForEach(func(key string, val interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// randomKey 从分段中随机取一个 key，分段为空时返回 false
+func (s *shard) randomKey() (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	// map 的遍历顺序是随机的
+	for key := range s.m {
+		return key, true
+	}
+	return "", false
+}
+
+// RandomKeys 随机返回最多 limit 个 key，结果中可能有重复
+func (dict *ConcurrentDict) RandomKeys(limit int) []string {
+	if limit <= 0 || dict.Len() == 0 {
+		return nil
+	}
+	shardCount := len(dict.table)
+	keys := make([]string, 0, limit)
+	// 字典在此期间可能被清空，限制尝试次数避免死循环
+	for tries := 0; len(keys) < limit && tries < limit*shardCount; tries++ {
+		s := dict.getShard(uint32(rand.Intn(shardCount)))
+		if key, ok := s.randomKey(); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// toLockIndices 计算 keys 所在的分段下标并升序排列，所有调用方按相同顺序加锁避免死锁
+func (dict *ConcurrentDict) toLockIndices(keys []string, reverse bool) []uint32 {
+	indexMap := make(map[uint32]struct{})
+	for _, key := range keys {
+		indexMap[dict.spread(key)] = struct{}{}
+	}
+	indices := make([]uint32, 0, len(indexMap))
+	for index := range indexMap {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		if reverse {
+			return indices[i] > indices[j]
+		}
+		return indices[i] < indices[j]
+	})
+	return indices
+}
+
+// RWLocks 锁住多个 key 所在的分段，writeKeys 加写锁，readKeys 加读锁
+// 同一个分段既有写又有读时加写锁。用于 MSET、RENAME 等涉及多个 key 的命令，
+// 加锁期间只能使用 XxxWithLock 方法访问这些 key
+func (dict *ConcurrentDict) RWLocks(writeKeys []string, readKeys []string) {
+	keys := append(append([]string(nil), writeKeys...), readKeys...)
+	writeIndices := dict.writeIndexSet(writeKeys)
+	for _, index := range dict.toLockIndices(keys, false) {
+		s := dict.getShard(index)
+		if _, w := writeIndices[index]; w {
+			s.mutex.Lock()
+		} else {
+			s.mutex.RLock()
+		}
+	}
+}
+
+// RWUnLocks 释放 RWLocks 加的锁，参数必须和加锁时相同
+func (dict *ConcurrentDict) RWUnLocks(writeKeys []string, readKeys []string) {
+	keys := append(append([]string(nil), writeKeys...), readKeys...)
+	writeIndices := dict.writeIndexSet(writeKeys)
+	for _, index := range dict.toLockIndices(keys, true) {
+		s := dict.getShard(index)
+		if _, w := writeIndices[index]; w {
+			s.mutex.Unlock()
+		} else {
+			s.mutex.RUnlock()
+		}
+	}
+}
+
+func (dict *ConcurrentDict) writeIndexSet(writeKeys []string) map[uint32]struct{} {
+	set := make(map[uint32]struct{}, len(writeKeys))
+	for _, key := range writeKeys {
+		set[dict.spread(key)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// sameShardKeys 返回 n 个落在同一个分段的 key
+func sameShardKeys(dict *ConcurrentDict, n int) []string {
+	byShard := make(map[uint32][]string)
+	for i := 0; ; i++ {
+		key := "key" + strconv.Itoa(i)
+		index := dict.spread(key)
+		byShard[index] = append(byShard[index], key)
+		if len(byShard[index]) == n {
+			return byShard[index]
+		}
+	}
+}
+
+// assertUnlocked 检查所有分段都没有被锁住
+func assertUnlocked(t *testing.T, dict *ConcurrentDict) {
+	t.Helper()
+	for i, s := range dict.table {
+		if !s.mutex.TryLock() {
+			t.Fatalf("shard %d is still locked", i)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func TestComputeCapacity(t *testing.T) {
+	for param, want := range map[int]int{0: 16, 16: 16, 17: 32, 1000: 1024, 1 << 16: 1 << 16} {
+		if got := computeCapacity(param); got != want {
+			t.Errorf("computeCapacity(%d) = %d, want %d", param, got, want)
+		}
+	}
+}
+
+func TestConcurrentDictCountAlignment(t *testing.T) {
+	if off := unsafe.Offsetof(ConcurrentDict{}.count); off != 0 {
+		t.Fatalf("count is at offset %d, want 0 for 64-bit atomic alignment", off)
+	}
+}
+
+func TestConcurrentDictPut(t *testing.T) {
+	dict := MakeConcurrent(16)
+	if n := dict.Put("a", 1); n != 1 {
+		t.Fatalf("Put new key = %d, want 1", n)
+	}
+	if n := dict.Put("a", 2); n != 0 {
+		t.Fatalf("Put existing key = %d, want 0", n)
+	}
+	if n := dict.PutIfAbsent("a", 3); n != 0 {
+		t.Fatalf("PutIfAbsent existing key = %d, want 0", n)
+	}
+	if n := dict.PutIfExists("b", 3); n != 0 {
+		t.Fatalf("PutIfExists missing key = %d, want 0", n)
+	}
+	if n := dict.PutIfAbsent("b", 4); n != 1 {
+		t.Fatalf("PutIfAbsent missing key = %d, want 1", n)
+	}
+	if n := dict.PutIfExists("b", 5); n != 1 {
+		t.Fatalf("PutIfExists existing key = %d, want 1", n)
+	}
+	if v, ok := dict.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get(a) = %v, %v, want 2", v, ok)
+	}
+	if v, ok := dict.Get("b"); !ok || v != 5 {
+		t.Fatalf("Get(b) = %v, %v, want 5", v, ok)
+	}
+	if dict.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", dict.Len())
+	}
+
+	if n := dict.Remove("a"); n != 1 {
+		t.Fatalf("Remove existing key = %d, want 1", n)
+	}
+	if n := dict.Remove("a"); n != 0 {
+		t.Fatalf("Remove missing key = %d, want 0", n)
+	}
+	if _, ok := dict.Get("a"); ok || dict.Len() != 1 {
+		t.Fatalf("key still present after Remove, Len = %d", dict.Len())
+	}
+}
+
+func TestConcurrentDictConcurrentPut(t *testing.T) {
+	dict := MakeConcurrent(16)
+	const workers, perWorker = 8, 500
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				// 每个 key 被两个协程写入，只计数一次
+				dict.Put(strconv.Itoa(w/2*perWorker+i), i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if got, want := dict.Len(), workers/2*perWorker; got != want {
+		t.Fatalf("Len = %d, want %d", got, want)
+	}
+	if got := len(dict.Keys()); got != dict.Len() {
+		t.Fatalf("len(Keys) = %d, Len = %d", got, dict.Len())
+	}
+}
+
+func TestConcurrentDictForEach(t *testing.T) {
+	dict := MakeConcurrent(16)
+	for i := 0; i < 100; i++ {
+		dict.Put(strconv.Itoa(i), i)
+	}
+	visited := 0
+	dict.ForEach(func(key string, val interface{}) bool {
+		visited++
+		return visited < 10
+	})
+	if visited != 10 {
+		t.Fatalf("ForEach visited %d entries after the consumer stopped at 10", visited)
+	}
+
+	keys := dict.RandomKeys(5)
+	if len(keys) != 5 {
+		t.Fatalf("RandomKeys(5) returned %d keys", len(keys))
+	}
+	for _, key := range keys {
+		if _, ok := dict.Get(key); !ok {
+			t.Fatalf("RandomKeys returned missing key %q", key)
+		}
+	}
+	if keys := MakeConcurrent(16).RandomKeys(5); len(keys) != 0 {
+		t.Fatalf("RandomKeys on an empty dict = %v", keys)
+	}
+}
+
+func TestConcurrentDictRWLocks(t *testing.T) {
+	dict := MakeConcurrent(16)
+	shared := sameShardKeys(dict, 3)
+
+	for _, tc := range []struct {
+		name       string
+		writeKeys  []string
+		readKeys   []string
+		wantWrites []string
+	}{
+		{"duplicate write keys", []string{"a", "a"}, nil, []string{"a"}},
+		{"duplicate read keys", nil, []string{"a", "a"}, nil},
+		{"write and read the same key", []string{"a"}, []string{"a"}, []string{"a"}},
+		{"write keys sharing a shard", shared[:2], nil, shared[:2]},
+		{"write and read sharing a shard", shared[:1], shared[1:], shared[:1]},
+		{"read then write sharing a shard", shared[1:2], []string{shared[0], shared[2]}, shared[1:2]},
+	} {
+		dict.RWLocks(tc.writeKeys, tc.readKeys)
+		for _, key := range tc.wantWrites {
+			dict.PutWithLock(key, tc.name)
+			if v, ok := dict.GetWithLock(key); !ok || v != tc.name {
+				t.Fatalf("%s: GetWithLock(%q) = %v, %v", tc.name, key, v, ok)
+			}
+		}
+		// 写锁住的分段不能再加读锁
+		for _, key := range tc.wantWrites {
+			if s := dict.getShard(dict.spread(key)); s.mutex.TryRLock() {
+				s.mutex.RUnlock()
+				t.Fatalf("%s: shard of %q is not write locked", tc.name, key)
+			}
+		}
+		dict.RWUnLocks(tc.writeKeys, tc.readKeys)
+		assertUnlocked(t, dict)
+	}
+}
+
+func TestConcurrentDictRWLocksNoDeadlock(t *testing.T) {
+	dict := MakeConcurrent(16)
+	keys := make([]string, 8)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+
+	// 以相反的顺序传入 key，加锁顺序一致就不会死锁
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			writeKeys, readKeys := keys[:4], reversed[:4]
+			if w%2 == 1 {
+				writeKeys, readKeys = reversed, keys
+			}
+			for i := 0; i < 200; i++ {
+				dict.RWLocks(writeKeys, readKeys)
+				for _, key := range writeKeys {
+					dict.PutWithLock(key, i)
+				}
+				dict.RWUnLocks(writeKeys, readKeys)
+			}
+		}(w)
+	}
+	wg.Wait()
+	assertUnlocked(t, dict)
+	if dict.Len() != len(keys) {
+		t.Fatalf("Len = %d, want %d", dict.Len(), len(keys))
+	}
+}
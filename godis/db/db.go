@@ -1 +1,88 @@
 package db
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"godis/redis/reply"
+)
+
+// DB 存储引擎，执行客户端发来的命令
+type DB interface {
+	Exec(args [][]byte) reply.Reply
+	Close() error
+}
+
+// ExecFunc 命令的执行函数, args 不包括命令名
+type ExecFunc func(db *StandaloneDB, args [][]byte) reply.Reply
+
+type command struct {
+	executor ExecFunc
+	// 参数数量(包括命令名)，大于 0 表示必须等于 arity，小于 0 表示至少 -arity 个
+	arity int
+}
+
+var cmdTable = make(map[string]*command)
+
+// RegisterCommand 注册命令，只能在 init 中调用
+func RegisterCommand(name string, executor ExecFunc, arity int) {
+	cmdTable[strings.ToLower(name)] = &command{
+		executor: executor,
+		arity:    arity,
+	}
+}
+
+// StandaloneDB 单机内存数据库
+type StandaloneDB struct {
+	data Dict
+}
+
+const dataDictSize = 1 << 16
+
+func NewStandaloneDB() *StandaloneDB {
+	return &StandaloneDB{data: MakeConcurrent(dataDictSize)}
+}
+
+// Exec 执行一条命令, args[0] 是命令名，args 不能为空
+func (db *StandaloneDB) Exec(args [][]byte) (result reply.Reply) {
+	defer func() {
+		// 某条命令的 bug 不能拖垮整个服务
+		if err := recover(); err != nil {
+			log.Println(fmt.Sprintf("exec panic: %v\n%s", err, debug.Stack()))
+			result = reply.MakeUnknownErrReply()
+		}
+	}()
+
+	name := strings.ToLower(string(args[0]))
+	cmd, ok := cmdTable[name]
+	if !ok {
+		return reply.MakeErrReply("ERR unknown command '" + sanitize(name) + "'")
+	}
+	if !validateArity(cmd.arity, args) {
+		return reply.MakeArgNumErrReply(name)
+	}
+	return cmd.executor(db, args[1:])
+}
+
+func (db *StandaloneDB) Close() error {
+	return nil
+}
+
+func validateArity(arity int, args [][]byte) bool {
+	if arity >= 0 {
+		return len(args) == arity
+	}
+	return len(args) >= -arity
+}
+
+// sanitize 替换客户端数据中的换行，避免拼进回复之后破坏 RESP 的格式
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, s)
+}
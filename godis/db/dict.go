@@ -0,0 +1,26 @@
+package db
+
+// Consumer 遍历字典时对每个键值对调用，返回 false 时停止遍历
+type Consumer func(key string, val interface{}) bool
+
+// Dict 键空间使用的字典
+// Put 系列方法返回新增(或者修改)的键的数量，Remove 返回删除的键的数量
+type Dict interface {
+	Get(key string) (val interface{}, exists bool)
+	Len() int
+	Put(key string, val interface{}) (result int)
+	PutIfAbsent(key string, val interface{}) (result int)
+	PutIfExists(key string, val interface{}) (result int)
+	Remove(key string) (result int)
+	ForEach(consumer Consumer)
+	Keys() []string
+	RandomKeys(limit int) []string
+
+	// RWLocks 按固定顺序锁住多个key，用于 MSET、RENAME 等涉及多个key的命令，
+	// 加锁期间只能使用 XxxWithLock 方法访问这些key
+	RWLocks(writeKeys []string, readKeys []string)
+	RWUnLocks(writeKeys []string, readKeys []string)
+	GetWithLock(key string) (val interface{}, exists bool)
+	PutWithLock(key string, val interface{}) (result int)
+	RemoveWithLock(key string) (result int)
+}
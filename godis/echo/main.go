@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/limitzhang87/go-utils/atomic"
+	sync2 "github.com/limitzhang87/go-utils/sync"
+	"godis/tcp"
+)
+
+// 客户端连接抽象
+type Client struct {
+	// tcp 连接
+	Conn net.Conn
+
+	// 带有 timeout 功能的WaitGroup， 用于优雅关闭
+	// 当响应被完成发送前保持 waiting 状态，阻止连接被关闭
+	Waiting sync2.Wait
+}
+
+type EchoHandle struct {
+	// 保存所有工作状态的client集合(把map当成set用)
+	// 需要用并发安全的容器
+	activeConn sync.Map
+
+	// 和 tcp server 中作用相同的关闭状态标志位
+	closing atomic.BoolAtomic
+}
+
+func NewEchoHandle() *EchoHandle {
+	return &EchoHandle{}
+}
+
+// 关闭客户端
+func (c *Client) Close() error {
+	// 等待数据发送完成或者超时
+	c.Waiting.WaitWithTimeout(10 * time.Second)
+	_ = c.Conn.Close()
+	return nil
+}
+
+// 处理函数
+func (h *EchoHandle) Handle(ctx context.Context, conn net.Conn) {
+	// 如果刚到当前 listener 广告关闭了，当前连接直接关闭
+	if h.closing.Get() {
+		_ = conn.Close()
+		return
+	}
+
+	client := &Client{
+		Conn: conn,
+	}
+	h.activeConn.Store(client, 1)
+	reader := bufio.NewReader(client.Conn) // 将连接放入缓冲池中
+	for {
+		msg, err := reader.ReadString('\n') // 从缓冲池中读取数据
+		if err != nil {
+			if err == io.EOF { // 连接关闭
+				log.Println("connection close")
+				h.activeConn.Delete(client)
+			} else {
+				log.Println(err)
+			}
+			return
+		}
+		// 发送数据前先设置waiting状态
+		client.Waiting.Add(1)
+
+		// 模拟关闭时未完成的发送
+		//time.Sleep(10 * time.Second)
+
+		_, _ = client.Conn.Write([]byte(msg))
+		client.Waiting.Done() // 发送结束
+	}
+}
+
+// 遍历所有连接并执行关闭，如果当前连接正在发送消息，会等待发送结束或者超时
+func (h *EchoHandle) Close() error {
+	log.Println("handler shutting down...")
+	h.closing.Set(true)
+	h.activeConn.Range(func(key, value interface{}) bool {
+		client := key.(*Client)
+		_ = client.Close()
+		return true
+	})
+	return nil
+}
+
+func main() {
+	echoHandle := NewEchoHandle()
+	config := &tcp.Config{Address: ":8000"}
+	if err := tcp.ListenAndServeWithSignal(config, echoHandle); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,3 +1,7 @@
 module godis
 
-go 1.14
+go 1.18
+
+require github.com/limitzhang87/go-utils v0.0.0
+
+replace github.com/limitzhang87/go-utils => ../
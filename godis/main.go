@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+
+	"godis/redis/server"
+	"godis/tcp"
+)
+
+func main() {
+	config := &tcp.Config{Address: ":6379"}
+	if err := tcp.ListenAndServeWithSignal(config, server.MakeHandler()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,325 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+
+	"godis/redis/reply"
+)
+
+const (
+	// 单个 bulk string 的最大长度, 和 redis 的 proto-max-bulk-len 默认值一致
+	maxBulkLen = 512 * 1024 * 1024
+	// 数组的最大长度
+	maxMultiBulkLen = 1024 * 1024
+	// 单行(包括 inline 命令)的最大长度
+	maxLineLen = 64 * 1024
+	// 数组的最大嵌套层数，客户端发来的命令不需要嵌套，限制层数避免递归导致栈溢出
+	maxNestingDepth = 8
+	// 超过这个长度的 bulk string 随着数据到达逐步扩容，不按声明的长度一次性分配
+	bulkChunkSize = 64 * 1024
+	// 数组按声明的长度预分配的上限，更长的数组随着元素到达扩容
+	maxPrealloc = 1024
+)
+
+// Payload 解析出的一条消息或者错误
+type Payload struct {
+	Data reply.Reply
+	Err  error
+}
+
+// ProtocolError 客户端发来的数据不符合 RESP 协议
+// 错误刚好结束在一行的末尾并且不在数组或者 bulk string 中时，解析器丢弃这一行继续解析，
+// 否则无法确定下一条消息从哪里开始，解析器发送错误之后停止，和 redis 一样应该关闭连接
+type ProtocolError struct {
+	Msg string
+	// 解析器是否会继续解析后面的数据
+	recoverable bool
+}
+
+func (e *ProtocolError) Error() string {
+	return "ERR Protocol error: " + e.Msg
+}
+
+// IsProtocolError err 是否是协议错误
+func IsProtocolError(err error) bool {
+	var pe *ProtocolError
+	return errors.As(err, &pe)
+}
+
+// IsRecoverable 协议错误之后解析器是否还会继续工作
+func IsRecoverable(err error) bool {
+	var pe *ProtocolError
+	return errors.As(err, &pe) && pe.recoverable
+}
+
+// recoverable 把一行内的协议错误标记为可以继续解析
+func recoverable(err error) error {
+	var pe *ProtocolError
+	if errors.As(err, &pe) {
+		pe.recoverable = true
+	}
+	return err
+}
+
+func protocolError(format string, args ...interface{}) error {
+	return &ProtocolError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// ParseStream 在新的 goroutine 中从 reader 读取 RESP2 数据并解析
+// 解析出的消息按顺序写入返回的通道，协议错误以 Payload.Err 的形式写入，
+// 可以恢复的协议错误(见 IsRecoverable)之后继续解析，
+// 其他协议错误和读取错误(包括 io.EOF)写入之后关闭通道
+func ParseStream(reader io.Reader) <-chan *Payload {
+	ch := make(chan *Payload)
+	go parse0(reader, ch)
+	return ch
+}
+
+func parse0(rawReader io.Reader, ch chan<- *Payload) {
+	defer func() {
+		// 解析器的 bug 不能拖垮整个服务
+		if err := recover(); err != nil {
+			log.Println(fmt.Sprintf("parser panic: %v\n%s", err, debug.Stack()))
+			ch <- &Payload{Err: fmt.Errorf("parser panic: %v", err)}
+		}
+		close(ch)
+	}()
+
+	reader := bufio.NewReader(rawReader)
+	for {
+		r, err := readReply(reader)
+		if err != nil {
+			ch <- &Payload{Err: err}
+			if IsRecoverable(err) {
+				continue
+			}
+			return
+		}
+		ch <- &Payload{Data: r}
+	}
+}
+
+// readLine 读取以 \r\n 结尾的一行，返回的内容不包括 \r\n
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := readRawLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, protocolError("line not terminated by CRLF: %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// readRawLine 读取以 \n 结尾的一行，返回的内容包括行尾的 \n
+func readRawLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// 行比缓冲区长，继续读完这一行
+		buf := append([]byte(nil), line...)
+		for err == bufio.ErrBufferFull && len(buf) <= maxLineLen {
+			line, err = reader.ReadSlice('\n')
+			buf = append(buf, line...)
+		}
+		line = buf
+	}
+	if err != nil && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if len(line) > maxLineLen {
+		// 丢掉这一行剩下的部分
+		for err == bufio.ErrBufferFull {
+			_, err = reader.ReadSlice('\n')
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		return nil, protocolError("too big inline request")
+	}
+	return line, nil
+}
+
+// readReply 读取并解析一条完整的消息
+func readReply(reader *bufio.Reader) (reply.Reply, error) {
+	for {
+		line, err := readRawLine(reader)
+		if err != nil {
+			// 出错的行已经完整读掉了，下一行是新的消息
+			return nil, recoverable(err)
+		}
+		// 和 redis 一样，inline 命令可以只用 \n 结尾，例如 nc 中输入的 PING
+		crlf := len(line) >= 2 && line[len(line)-2] == '\r'
+		if crlf {
+			line = line[:len(line)-2]
+		} else {
+			line = line[:len(line)-1]
+		}
+		if len(line) == 0 {
+			// 空行，跳过
+			continue
+		}
+		if isTypePrefix(line[0]) && !crlf {
+			return nil, recoverable(protocolError("line not terminated by CRLF: %q", line))
+		}
+		switch line[0] {
+		case '+', '-', ':':
+			// 单行的消息出错也不影响后面的数据
+			r, err := readTyped(reader, line, 0)
+			if err != nil {
+				return nil, recoverable(err)
+			}
+			return r, nil
+		case '$', '*':
+			return readTyped(reader, line, 0)
+		}
+		// inline 命令, 例如 telnet 中直接输入的 PING
+		// line 指向 bufio 的缓冲区，需要复制一份
+		args := bytes.Fields(append([]byte(nil), line...))
+		if len(args) == 0 {
+			continue
+		}
+		return reply.MakeMultiBulkReply(args), nil
+	}
+}
+
+func isTypePrefix(b byte) bool {
+	switch b {
+	case '+', '-', ':', '$', '*':
+		return true
+	}
+	return false
+}
+
+// readTyped 根据首行的类型前缀解析一条消息，depth 是所在数组的嵌套层数
+func readTyped(reader *bufio.Reader, line []byte, depth int) (reply.Reply, error) {
+	switch line[0] {
+	case '+':
+		return reply.MakeStatusReply(string(line[1:])), nil
+	case '-':
+		return reply.MakeErrReply(string(line[1:])), nil
+	case ':':
+		code, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return nil, protocolError("illegal number %q", line[1:])
+		}
+		return reply.MakeIntReply(code), nil
+	case '$':
+		arg, err := readBulkBody(reader, line)
+		if err != nil {
+			return nil, err
+		}
+		if arg == nil {
+			return reply.MakeNullBulkReply(), nil
+		}
+		return reply.MakeBulkReply(arg), nil
+	default:
+		return readMultiBulk(reader, line, depth)
+	}
+}
+
+// readBulkBody 根据 $ 开头的行读取 bulk string 的内容, $-1 返回nil
+// 使用 io.ReadFull 读取，数据分多次到达也能正确处理
+func readBulkBody(reader *bufio.Reader, header []byte) ([]byte, error) {
+	n, err := strconv.ParseInt(string(header[1:]), 10, 64)
+	if err != nil {
+		return nil, protocolError("invalid bulk length %q", header[1:])
+	}
+	if n == -1 {
+		return nil, nil
+	}
+	if n < 0 || n > maxBulkLen {
+		return nil, protocolError("invalid bulk length %d", n)
+	}
+	var body []byte
+	if n+2 <= bulkChunkSize {
+		body = make([]byte, n+2)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return nil, err
+		}
+	} else {
+		// 只凭声明的长度就分配大块内存，几个连接就能耗尽内存，所以随着数据到达逐步扩容
+		var buf bytes.Buffer
+		buf.Grow(bulkChunkSize)
+		if _, err := io.CopyN(&buf, reader, n+2); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+	if body[n] != '\r' || body[n+1] != '\n' {
+		return nil, protocolError("bulk string not terminated by CRLF")
+	}
+	return body[:n], nil
+}
+
+// readMultiBulk 根据 * 开头的行读取数组
+// 元素全部是 bulk string 时返回 MultiBulkReply，否则返回可以嵌套的 MultiRawReply
+func readMultiBulk(reader *bufio.Reader, header []byte, depth int) (reply.Reply, error) {
+	if depth >= maxNestingDepth {
+		return nil, protocolError("too many nested arrays")
+	}
+	n, err := strconv.ParseInt(string(header[1:]), 10, 64)
+	if err != nil {
+		return nil, protocolError("invalid multibulk length %q", header[1:])
+	}
+	if n <= 0 {
+		// *0 和 *-1 都当作空数组
+		if n < -1 {
+			return nil, protocolError("invalid multibulk length %d", n)
+		}
+		return reply.MakeEmptyMultiBulkReply(), nil
+	}
+	if n > maxMultiBulkLen {
+		return nil, protocolError("invalid multibulk length %d", n)
+	}
+
+	// 声明的长度不可信，预分配的大小有上限
+	size := n
+	if size > maxPrealloc {
+		size = maxPrealloc
+	}
+	args := make([][]byte, 0, size)
+	var replies []reply.Reply
+	for i := int64(0); i < n; i++ {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || !isTypePrefix(line[0]) {
+			return nil, protocolError("expected type prefix, got %q", line)
+		}
+		if line[0] == '$' && replies == nil {
+			arg, err := readBulkBody(reader, line)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			continue
+		}
+		if replies == nil {
+			// 出现了非 bulk 元素，之前读到的 bulk 转换成 reply
+			replies = make([]reply.Reply, 0, size)
+			for _, arg := range args {
+				replies = append(replies, reply.MakeBulkReply(arg))
+			}
+		}
+		r, err := readTyped(reader, line, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, r)
+	}
+	if replies != nil {
+		return reply.MakeMultiRawReply(replies), nil
+	}
+	return reply.MakeMultiBulkReply(args), nil
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"godis/redis/reply"
+)
+
+// collect 读出 ParseStream 的所有结果，结束时的读取错误(通常是 io.EOF)不计入
+func collect(t *testing.T, r io.Reader) ([]reply.Reply, []error) {
+	t.Helper()
+	var replies []reply.Reply
+	var errs []error
+	for payload := range ParseStream(r) {
+		if payload.Err != nil {
+			if payload.Err != io.EOF {
+				errs = append(errs, payload.Err)
+			}
+			continue
+		}
+		replies = append(replies, payload.Data)
+	}
+	return replies, errs
+}
+
+func TestParseStream(t *testing.T) {
+	// 按 RESP 编码的消息解析之后重新编码应该得到同样的数据
+	messages := []string{
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+		"+OK\r\n",
+		"-ERR unknown\r\n",
+		":-42\r\n",
+		"$-1\r\n",
+		"$0\r\n\r\n",
+		"$4\r\na\r\nb\r\n",
+		"*0\r\n",
+		"*2\r\n:1\r\n*2\r\n$1\r\na\r\n+b\r\n",
+	}
+	input := strings.Join(messages, "")
+	for name, r := range map[string]io.Reader{
+		"whole":   strings.NewReader(input),
+		"onebyte": iotest.OneByteReader(strings.NewReader(input)),
+	} {
+		replies, errs := collect(t, r)
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %v", name, errs)
+		}
+		if len(replies) != len(messages) {
+			t.Fatalf("%s: got %d replies, want %d", name, len(replies), len(messages))
+		}
+		for i, r := range replies {
+			if got := string(r.ToBytes()); got != messages[i] {
+				t.Errorf("%s: reply %d = %q, want %q", name, i, got, messages[i])
+			}
+		}
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	// 空行被跳过，只用 \n 结尾的 inline 命令也能解析
+	replies, errs := collect(t, strings.NewReader("PING\r\n\r\n  set  k v \nPING\n"))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	want := [][]string{{"PING"}, {"set", "k", "v"}, {"PING"}}
+	if len(replies) != len(want) {
+		t.Fatalf("got %d replies, want %d", len(replies), len(want))
+	}
+	for i, r := range replies {
+		mb, ok := r.(*reply.MultiBulkReply)
+		if !ok || len(mb.Args) != len(want[i]) {
+			t.Fatalf("reply %d = %q, want %q", i, r.ToBytes(), want[i])
+		}
+		for j, arg := range mb.Args {
+			if string(arg) != want[i][j] {
+				t.Errorf("reply %d arg %d = %q, want %q", i, j, arg, want[i][j])
+			}
+		}
+	}
+}
+
+func TestParseLargeBulk(t *testing.T) {
+	// 超过 bulkChunkSize 的 bulk string 分多次到达
+	value := bytes.Repeat([]byte("0123456789"), 3*bulkChunkSize/10)
+	pr, pw := io.Pipe()
+	go func() {
+		var msg bytes.Buffer
+		msg.WriteString("*2\r\n$3\r\nGET\r\n")
+		msg.WriteString("$" + strconv.Itoa(len(value)) + "\r\n")
+		msg.Write(value)
+		msg.WriteString("\r\n")
+		data := msg.Bytes()
+		for len(data) > 0 {
+			n := 1000
+			if n > len(data) {
+				n = len(data)
+			}
+			_, _ = pw.Write(data[:n])
+			data = data[n:]
+		}
+		_ = pw.Close()
+	}()
+
+	replies, errs := collect(t, pr)
+	if len(errs) != 0 || len(replies) != 1 {
+		t.Fatalf("got %d replies and errors %v, want 1 reply", len(replies), errs)
+	}
+	mb := replies[0].(*reply.MultiBulkReply)
+	if len(mb.Args) != 2 || !bytes.Equal(mb.Args[1], value) {
+		t.Fatalf("large bulk string was not parsed intact")
+	}
+}
+
+func TestParseRecoverableErrors(t *testing.T) {
+	// 错误结束在行尾的时候丢弃这一行继续解析
+	for _, input := range []string{
+		":abc\r\n",
+		"*2\n",
+		"$3\n",
+		strings.Repeat("x", maxLineLen+1) + "\r\n",
+	} {
+		replies, errs := collect(t, strings.NewReader(input+"PING\r\n"))
+		if len(errs) != 1 || !IsRecoverable(errs[0]) {
+			t.Errorf("%.20q: errors %v, want one recoverable protocol error", input, errs)
+			continue
+		}
+		if len(replies) != 1 || string(replies[0].ToBytes()) != "*1\r\n$4\r\nPING\r\n" {
+			t.Errorf("%.20q: parser did not continue after the error", input)
+		}
+	}
+}
+
+func TestParseFatalErrors(t *testing.T) {
+	// 消息中间出错之后无法对齐后面的数据，解析器停止
+	for _, input := range []string{
+		"*2\r\n$3\r\nfoo\r\nbar\r\n",
+		"*1\r\n$3\r\nfooo\r\n",
+		"$-2\r\n",
+		"*-2\r\n",
+		"*1\r\n$x\r\n",
+		strings.Repeat("*1\r\n", maxNestingDepth+1) + ":1\r\n",
+		"$" + strconv.Itoa(maxBulkLen+1) + "\r\n",
+		"*" + strconv.Itoa(maxMultiBulkLen+1) + "\r\n",
+	} {
+		replies, errs := collect(t, strings.NewReader(input+"PING\r\n"))
+		if len(errs) != 1 || !IsProtocolError(errs[0]) || IsRecoverable(errs[0]) {
+			t.Errorf("%.20q: errors %v, want one fatal protocol error", input, errs)
+		}
+		if len(replies) != 0 {
+			t.Errorf("%.20q: parser continued after a fatal error", input)
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	// 声明了很长的数组但是数据不足，不能按声明的长度分配内存
+	for _, input := range []string{
+		"*1000000\r\n$3\r\nGET\r\n",
+		"*2\r\n$3\r\nGET\r\n$100",
+		"$10\r\nabc",
+	} {
+		var payloads []*Payload
+		for payload := range ParseStream(strings.NewReader(input)) {
+			payloads = append(payloads, payload)
+		}
+		if len(payloads) != 1 {
+			t.Errorf("%q: got %d payloads, want only the read error", input, len(payloads))
+			continue
+		}
+		if err := payloads[0].Err; err != io.EOF && err != io.ErrUnexpectedEOF {
+			t.Errorf("%q: err = %v, want EOF", input, err)
+		}
+	}
+}
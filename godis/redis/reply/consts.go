@@ -0,0 +1,76 @@
+package reply
+
+// 常用的回复是无状态的，使用共享的单例，避免每条命令都分配内存
+
+var (
+	okBytes             = []byte("+OK\r\n")
+	pongBytes           = []byte("+PONG\r\n")
+	nullBulkBytes       = []byte("$-1\r\n")
+	emptyMultiBulkBytes = []byte("*0\r\n")
+	noBytes             = []byte("")
+)
+
+// OkReply +OK
+type OkReply struct{}
+
+var theOkReply = new(OkReply)
+
+func MakeOkReply() *OkReply {
+	return theOkReply
+}
+
+func (r *OkReply) ToBytes() []byte {
+	return okBytes
+}
+
+// PongReply +PONG
+type PongReply struct{}
+
+var thePongReply = new(PongReply)
+
+func MakePongReply() *PongReply {
+	return thePongReply
+}
+
+func (r *PongReply) ToBytes() []byte {
+	return pongBytes
+}
+
+// NullBulkReply 空的字符串 $-1, 也就是 nil
+type NullBulkReply struct{}
+
+var theNullBulkReply = new(NullBulkReply)
+
+func MakeNullBulkReply() *NullBulkReply {
+	return theNullBulkReply
+}
+
+func (r *NullBulkReply) ToBytes() []byte {
+	return nullBulkBytes
+}
+
+// EmptyMultiBulkReply 空数组 *0
+type EmptyMultiBulkReply struct{}
+
+var theEmptyMultiBulkReply = new(EmptyMultiBulkReply)
+
+func MakeEmptyMultiBulkReply() *EmptyMultiBulkReply {
+	return theEmptyMultiBulkReply
+}
+
+func (r *EmptyMultiBulkReply) ToBytes() []byte {
+	return emptyMultiBulkBytes
+}
+
+// NoReply 不需要回复, 例如 subscribe 之类自己写回复的命令
+type NoReply struct{}
+
+var theNoReply = new(NoReply)
+
+func MakeNoReply() *NoReply {
+	return theNoReply
+}
+
+func (r *NoReply) ToBytes() []byte {
+	return noBytes
+}
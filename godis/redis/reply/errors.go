@@ -0,0 +1,92 @@
+package reply
+
+// UnknownErrReply 未知错误
+type UnknownErrReply struct{}
+
+var unknownErrBytes = []byte("-ERR unknown\r\n")
+
+var theUnknownErrReply = new(UnknownErrReply)
+
+func MakeUnknownErrReply() *UnknownErrReply {
+	return theUnknownErrReply
+}
+
+func (r *UnknownErrReply) ToBytes() []byte {
+	return unknownErrBytes
+}
+
+func (r *UnknownErrReply) Error() string {
+	return "ERR unknown"
+}
+
+// ArgNumErrReply 命令参数数量错误
+type ArgNumErrReply struct {
+	Cmd string
+}
+
+func MakeArgNumErrReply(cmd string) *ArgNumErrReply {
+	return &ArgNumErrReply{Cmd: cmd}
+}
+
+func (r *ArgNumErrReply) ToBytes() []byte {
+	return []byte("-" + r.Error() + CRLF)
+}
+
+func (r *ArgNumErrReply) Error() string {
+	return "ERR wrong number of arguments for '" + r.Cmd + "' command"
+}
+
+// SyntaxErrReply 语法错误
+type SyntaxErrReply struct{}
+
+var syntaxErrBytes = []byte("-ERR syntax error\r\n")
+
+var theSyntaxErrReply = new(SyntaxErrReply)
+
+func MakeSyntaxErrReply() *SyntaxErrReply {
+	return theSyntaxErrReply
+}
+
+func (r *SyntaxErrReply) ToBytes() []byte {
+	return syntaxErrBytes
+}
+
+func (r *SyntaxErrReply) Error() string {
+	return "ERR syntax error"
+}
+
+// WrongTypeErrReply 对key执行了不支持其数据类型的操作
+type WrongTypeErrReply struct{}
+
+var wrongTypeErrBytes = []byte("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+
+var theWrongTypeErrReply = new(WrongTypeErrReply)
+
+func MakeWrongTypeErrReply() *WrongTypeErrReply {
+	return theWrongTypeErrReply
+}
+
+func (r *WrongTypeErrReply) ToBytes() []byte {
+	return wrongTypeErrBytes
+}
+
+func (r *WrongTypeErrReply) Error() string {
+	return "WRONGTYPE Operation against a key holding the wrong kind of value"
+}
+
+// ProtocolErrReply 客户端发来的数据不符合协议
+type ProtocolErrReply struct {
+	Msg string
+}
+
+func MakeProtocolErrReply(msg string) *ProtocolErrReply {
+	return &ProtocolErrReply{Msg: msg}
+}
+
+func (r *ProtocolErrReply) ToBytes() []byte {
+	return []byte("-" + r.Error() + CRLF)
+}
+
+func (r *ProtocolErrReply) Error() string {
+	return "ERR Protocol error: '" + r.Msg + "'"
+}
@@ -0,0 +1,129 @@
+package reply
+
+import (
+	"bytes"
+	"strconv"
+)
+
+var (
+	CRLF = "\r\n"
+)
+
+// Reply RESP 协议的一条消息，既可以是客户端发来的命令，也可以是服务端的回复
+type Reply interface {
+	ToBytes() []byte
+}
+
+// ErrorReply 错误回复
+type ErrorReply interface {
+	Error() string
+	ToBytes() []byte
+}
+
+// IsErrorReply 回复是否是错误
+func IsErrorReply(r Reply) bool {
+	_, ok := r.(ErrorReply)
+	return ok
+}
+
+// StatusReply 简单字符串, 例如 +OK
+type StatusReply struct {
+	Status string
+}
+
+func MakeStatusReply(status string) *StatusReply {
+	return &StatusReply{Status: status}
+}
+
+func (r *StatusReply) ToBytes() []byte {
+	return []byte("+" + r.Status + CRLF)
+}
+
+// StandardErrReply 错误, 例如 -ERR unknown
+type StandardErrReply struct {
+	Status string
+}
+
+func MakeErrReply(status string) *StandardErrReply {
+	return &StandardErrReply{Status: status}
+}
+
+func (r *StandardErrReply) ToBytes() []byte {
+	return []byte("-" + r.Status + CRLF)
+}
+
+func (r *StandardErrReply) Error() string {
+	return r.Status
+}
+
+// IntReply 整数, 例如 :1
+type IntReply struct {
+	Code int64
+}
+
+func MakeIntReply(code int64) *IntReply {
+	return &IntReply{Code: code}
+}
+
+func (r *IntReply) ToBytes() []byte {
+	return []byte(":" + strconv.FormatInt(r.Code, 10) + CRLF)
+}
+
+// BulkReply 二进制安全的字符串, 例如 $3\r\nfoo\r\n
+type BulkReply struct {
+	Arg []byte
+}
+
+func MakeBulkReply(arg []byte) *BulkReply {
+	return &BulkReply{Arg: arg}
+}
+
+func (r *BulkReply) ToBytes() []byte {
+	if r.Arg == nil {
+		return nullBulkBytes
+	}
+	return []byte("$" + strconv.Itoa(len(r.Arg)) + CRLF + string(r.Arg) + CRLF)
+}
+
+// MultiBulkReply 由字符串组成的数组, 客户端发来的命令都是这种格式
+type MultiBulkReply struct {
+	Args [][]byte
+}
+
+func MakeMultiBulkReply(args [][]byte) *MultiBulkReply {
+	return &MultiBulkReply{Args: args}
+}
+
+func (r *MultiBulkReply) ToBytes() []byte {
+	if len(r.Args) == 0 {
+		return emptyMultiBulkBytes
+	}
+	var buf bytes.Buffer
+	buf.WriteString("*" + strconv.Itoa(len(r.Args)) + CRLF)
+	for _, arg := range r.Args {
+		if arg == nil {
+			buf.Write(nullBulkBytes)
+			continue
+		}
+		buf.WriteString("$" + strconv.Itoa(len(arg)) + CRLF + string(arg) + CRLF)
+	}
+	return buf.Bytes()
+}
+
+// MultiRawReply 由任意回复组成的数组，可以嵌套
+type MultiRawReply struct {
+	Replies []Reply
+}
+
+func MakeMultiRawReply(replies []Reply) *MultiRawReply {
+	return &MultiRawReply{Replies: replies}
+}
+
+func (r *MultiRawReply) ToBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("*" + strconv.Itoa(len(r.Replies)) + CRLF)
+	for _, rep := range r.Replies {
+		buf.Write(rep.ToBytes())
+	}
+	return buf.Bytes()
+}
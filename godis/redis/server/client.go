@@ -1,8 +1,8 @@
 package server
 
 import (
-	"godis/lib/sync/atomic"
-	"godis/lib/sync/wait"
+	"github.com/limitzhang87/go-utils/atomic"
+	sync2 "github.com/limitzhang87/go-utils/sync"
 	"net"
 	"sync"
 	"time"
@@ -14,10 +14,10 @@ type Client struct {
 
 	// 带有超时的 WaitGroup, 当相应被完整发送前保持
 	// 当响应被完整发送前保持 waiting 状态， 阻止链接被关闭
-	waitingReply wait.Wait
+	waitingReply sync2.Wait
 
 	/* 标记客户端正在发送指令 */
-	sending atomic.AtomicBool
+	sending atomic.BoolAtomic
 
 	/* 客户端正在发送的参数数量，即 Array 第一行指定的数组长度 */
 	expectedArgsCount uint32
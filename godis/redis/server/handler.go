@@ -1,19 +1,115 @@
 package server
 
 import (
-	"godis/lib/sync/atomic"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
 	"sync"
+
+	"github.com/limitzhang87/go-utils/atomic"
+	"godis/db"
+	"godis/redis/parser"
+	"godis/redis/reply"
 )
 
 var (
-	UnknownErrReplyBytes = []byte("-ERR unknown\r\n")
+	UnknownErrReplyBytes = reply.MakeUnknownErrReply().ToBytes()
 )
 
 type Handler struct {
 	// 正在链接中的客户端tcp
 	activeConn sync.Map
-	//db db.DB
+	db         db.DB
 
 	// 关闭状态
-	closing atomic.AtomicBool
+	closing atomic.BoolAtomic
+}
+
+func MakeHandler() *Handler {
+	return &Handler{db: db.NewStandaloneDB()}
+}
+
+// Handle 处理一个客户端连接，解析命令交给 db 执行并写回结果
+func (h *Handler) Handle(ctx context.Context, conn net.Conn) {
+	// 服务正在关闭，不再接受新的连接
+	if h.closing.Get() {
+		_ = conn.Close()
+		return
+	}
+
+	client := MakeClient(conn)
+	h.activeConn.Store(client, struct{}{})
+
+	ch := parser.ParseStream(conn)
+	defer func() {
+		h.closeClient(client)
+		// 连接关闭后解析协程会写入一个读取错误，取走剩下的数据让它退出
+		for range ch {
+		}
+	}()
+	for {
+		var payload *parser.Payload
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case payload, ok = <-ch:
+			if !ok {
+				return
+			}
+		}
+
+		if payload.Err != nil {
+			if parser.IsProtocolError(payload.Err) {
+				_ = client.Write(reply.MakeErrReply(payload.Err.Error()).ToBytes())
+				if parser.IsRecoverable(payload.Err) {
+					continue
+				}
+				// 消息中间出错，后面的数据已经无法对齐，和 redis 一样关闭连接
+				return
+			}
+			if payload.Err != io.EOF && !errors.Is(payload.Err, net.ErrClosed) {
+				log.Println(payload.Err)
+			}
+			return
+		}
+
+		if _, ok := payload.Data.(*reply.EmptyMultiBulkReply); ok {
+			// *0 和 *-1 是空命令，和 redis 一样忽略，不回复
+			continue
+		}
+		r, ok := payload.Data.(*reply.MultiBulkReply)
+		if !ok {
+			_ = client.Write(reply.MakeProtocolErrReply("expected array of bulk strings").ToBytes())
+			continue
+		}
+		// 从执行命令到回复发送完成期间保持 waiting 状态，关闭时等待回复写完
+		client.waitingReply.Add(1)
+		result := h.db.Exec(r.Args)
+		err := client.Write(result.ToBytes())
+		client.waitingReply.Done()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// closeClient 关闭单个客户端并从活跃连接中移除
+func (h *Handler) closeClient(client *Client) {
+	_ = client.Close()
+	h.activeConn.Delete(client)
+}
+
+// Close 关闭所有客户端和 db，正在发送的回复会等待发送完成或者超时
+func (h *Handler) Close() error {
+	log.Println("handler shutting down...")
+	h.closing.Set(true)
+	h.activeConn.Range(func(key, value interface{}) bool {
+		client := key.(*Client)
+		_ = client.Close()
+		return true
+	})
+	return h.db.Close()
 }
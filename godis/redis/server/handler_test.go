@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startHandler 在 net.Pipe 的一端运行 Handle，返回客户端一端和 Handle 结束时关闭的通道
+func startHandler(t *testing.T, h *Handler) (net.Conn, <-chan struct{}) {
+	t.Helper()
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Handle(context.Background(), server)
+	}()
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { _ = client.Close() })
+	return client, done
+}
+
+// roundTrip 发送 req 并读取 n 行回复
+func roundTrip(t *testing.T, conn net.Conn, reader *bufio.Reader, req string, n int) []string {
+	t.Helper()
+	if _, err := io.WriteString(conn, req); err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading reply to %q: %v", req, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestHandlerCommands(t *testing.T) {
+	h := MakeHandler()
+	conn, done := startHandler(t, h)
+	reader := bufio.NewReader(conn)
+
+	for _, tc := range []struct {
+		req  string
+		want []string
+	}{
+		// 空数组被忽略，不回复
+		{"*0\r\n*-1\r\nPING\r\n", []string{"+PONG\r\n"}},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n", []string{"+OK\r\n"}},
+		{"GET k\n", []string{"$1\r\n", "v\r\n"}},
+		{"FOO\r\n", []string{"-ERR unknown command 'foo'\r\n"}},
+		// 不是命令的消息回复错误，连接继续可用
+		{":1\r\n", []string{"-ERR Protocol error: 'expected array of bulk strings'\r\n"}},
+		{":x\r\n", []string{"-ERR Protocol error: illegal number \"x\"\r\n"}},
+		{"ECHO hi\r\n", []string{"$2\r\n", "hi\r\n"}},
+	} {
+		got := roundTrip(t, conn, reader, tc.req, len(tc.want))
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%q: reply %q, want %q", tc.req, got, tc.want)
+			}
+		}
+	}
+
+	_ = conn.Close()
+	<-done
+}
+
+func TestHandlerFatalProtocolError(t *testing.T) {
+	h := MakeHandler()
+	conn, done := startHandler(t, h)
+	reader := bufio.NewReader(conn)
+
+	// 消息中间出错，回复错误之后关闭连接
+	got := roundTrip(t, conn, reader, "*2\r\n$3\r\nGET\r\nk\r\n", 1)
+	if got[0] != "-ERR Protocol error: expected type prefix, got \"k\"\r\n" {
+		t.Fatalf("reply %q", got[0])
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("read after fatal error = %v, want EOF", err)
+	}
+	<-done
+}
+
+func TestHandlerClose(t *testing.T) {
+	h := MakeHandler()
+	conn, done := startHandler(t, h)
+	reader := bufio.NewReader(conn)
+	roundTrip(t, conn, reader, "PING\r\n", 1)
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("read after Close = %v, want EOF", err)
+	}
+
+	// 关闭之后新的连接直接断开
+	conn, done = startHandler(t, h)
+	<-done
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read on a conn accepted after Close = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,201 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/limitzhang87/go-utils/atomic"
+)
+
+var (
+	ErrServerClosed  = errors.New("tcp: server closed")
+	ErrServerStarted = errors.New("tcp: server already started")
+)
+
+// Accept 遇到临时错误时最长的重试间隔
+const maxAcceptDelay = time.Second
+
+type Config struct {
+	Address string
+}
+
+// Handler 应用层服务器
+type Handler interface {
+	Handle(ctx context.Context, conn net.Conn)
+	Close() error
+}
+
+// ListenAndServeWithSignal 监听 cfg.Address 并提供服务，收到中断信号后优雅关闭
+func ListenAndServeWithSignal(cfg *Config, handler Handler) error {
+	ctx, stop := signal.NotifyContext(context.Background(),
+		syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return fmt.Errorf("listen err: %w", err)
+	}
+	log.Println(fmt.Sprintf("bind: %s, start listenning...", cfg.Address))
+	return ListenAndServe(ctx, listener, handler)
+}
+
+// ListenAndServe 在 listener 上接受连接并交给 handler 处理，直到 ctx 被取消或者 Accept 出现非临时的错误
+// 退出前关闭 listener 和 handler，并等待所有连接处理结束
+// ctx 被取消时返回 nil，否则返回 Accept 的错误
+func ListenAndServe(ctx context.Context, listener net.Listener, handler Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 调用方取消 ctx 或者收到信号时进入关闭流程
+	var closing atomic.BoolAtomic
+	go func() {
+		<-ctx.Done()
+		// 设置标志位为关闭中，使用原子操作保证线程可见性
+		closing.Set(true)
+		// 先关闭 listener 防止新的连接进入
+		// listener 关闭之后， listener.Accept() 会立即返回错误
+		_ = listener.Close()
+	}()
+
+	// waitGroup的计数是当前存在的连接数
+	// 进入关闭流程时，应该等到所有连接关闭了再返回
+	var waitDone sync.WaitGroup
+	var serveErr error
+	// 临时错误(例如文件描述符耗尽)之后的等待时间，和 net/http 一样从 5ms 开始翻倍，最多 1s
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if closing.Get() {
+				break
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if tempDelay > maxAcceptDelay {
+					tempDelay = maxAcceptDelay
+				}
+				log.Println(fmt.Sprintf("accept err: %v; retrying in %v", err, tempDelay))
+				// 等待期间关闭的话，listener 已经被关闭，下一次 Accept 会立即返回
+				timer := time.NewTimer(tempDelay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+				}
+				continue
+			}
+			serveErr = fmt.Errorf("accept err: %w", err)
+			break
+		}
+		tempDelay = 0
+		// 必须在启动协程前计数，否则 Wait 可能先于 Add 返回
+		waitDone.Add(1)
+		go func() {
+			defer waitDone.Done()
+			handler.Handle(ctx, conn)
+		}()
+	}
+
+	log.Println("shutting down...")
+	// Accept 出错退出时也要关闭 listener，重复关闭没有影响
+	cancel()
+	_ = listener.Close()
+	// 逐个关闭已建立的连接，正在发送的回复会等待发送结束或者超时
+	_ = handler.Close()
+	log.Println("waiting disconnect...")
+	waitDone.Wait()
+	return serveErr
+}
+
+// Server 可以由调用方关闭的 TCP 服务
+type Server struct {
+	cfg     *Config
+	handler Handler
+
+	mu       sync.Mutex
+	listener net.Listener
+	cancel   context.CancelFunc
+	done     chan struct{}
+	started  bool
+	closed   bool
+}
+
+func NewServer(cfg *Config, handler Handler) *Server {
+	return &Server{
+		cfg:     cfg,
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+}
+
+// ListenAndServe 监听 cfg.Address 并提供服务，直到 ctx 被取消或者调用 Shutdown
+// 只能调用一次，Shutdown 之后调用返回 ErrServerClosed
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	if s.started {
+		s.mu.Unlock()
+		return ErrServerStarted
+	}
+	s.started = true
+	defer close(s.done)
+	listener, err := net.Listen("tcp", s.cfg.Address)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("listen err: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.listener = listener
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	log.Println(fmt.Sprintf("bind: %s, start listenning...", listener.Addr()))
+	return ListenAndServe(ctx, listener, s.handler)
+}
+
+// Addr 实际监听的地址，还没有开始监听时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Shutdown 停止接受新连接并关闭 handler，等待所有连接处理结束或者 ctx 结束
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	started := s.started
+	cancel := s.cancel
+	s.mu.Unlock()
+	if !started {
+		return nil
+	}
+	if cancel != nil {
+		cancel()
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"go-utils/atomic"
-	sync2 "go-utils/sync"
+	"github.com/limitzhang87/go-utils/atomic"
+	sync2 "github.com/limitzhang87/go-utils/sync"
 	"io"
 	"log"
 	"net"
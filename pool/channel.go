@@ -1,9 +1,10 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -11,6 +12,7 @@ import (
 var (
 	ErrMaxActiveConnReached = errors.New("MaxActiveConnReached")
 	ErrConnectionIsNil      = errors.New("connection is nil. rejecting")
+	ErrConnReturned         = errors.New("connection already returned to pool")
 )
 
 // Config 连接池相关配置
@@ -29,25 +31,88 @@ type Config struct {
 	Ping func(interface{}) error
 	// 连接最大空闲时间， 超过改时间则将失效
 	IdleTimeout time.Duration
+	// Get 时等待空闲连接的最长时间，0表示一直等待
+	WaitTimeout time.Duration
+	// 连接从创建开始的最长存活时间，0表示不限制
+	// 借出的连接按连接本身记录创建时间，不能作为map key的连接(例如 []byte)归还之后不再受这个限制
+	MaxLifetime time.Duration
+	// 什么时候调用 Ping 检查连接，默认只检查从空闲连接池取出的连接
+	TestPolicy TestPolicy
+	// 后台清理空闲连接的间隔，0表示不启动后台清理
+	ReapInterval time.Duration
+	// 后台清理时保持的最少空闲连接数，需要 ReapInterval > 0 才生效
+	MinIdle int
+	// 空闲连接的复用顺序，默认 FIFO
+	Strategy Strategy
+	// 连接池事件回调，可以为nil
+	Hook StatsHook
+}
+
+// Strategy 空闲连接的复用顺序
+type Strategy int
+
+const (
+	// FIFO 优先复用最早放回的连接，负载平均分布在所有连接上
+	FIFO Strategy = iota
+	// LIFO 优先复用最近放回的连接，其余连接可以因为空闲超时被回收
+	LIFO
+)
+
+// TestPolicy 连接检查策略，决定什么时候调用 Config.Ping
+type TestPolicy int
+
+const (
+	// TestOnIdle 只检查从空闲连接池取出的连接
+	TestOnIdle TestPolicy = iota
+	// TestOnBorrow 每次借出都检查，包括新建的连接和等待得到的连接
+	TestOnBorrow
+	// TestOnReturn 归还时检查，检查失败直接关闭
+	TestOnReturn
+	// TestAlways 借出和归还都检查
+	TestAlways
+	// TestNever 从不检查
+	TestNever
+)
+
+func (p TestPolicy) onIdle() bool {
+	return p == TestOnIdle || p == TestOnBorrow || p == TestAlways
+}
+
+func (p TestPolicy) onBorrow() bool {
+	return p == TestOnBorrow || p == TestAlways
+}
+
+func (p TestPolicy) onReturn() bool {
+	return p == TestOnReturn || p == TestAlways
 }
 
 // 封装连接实例
 type idleConn struct {
 	conn interface{}
-	t    time.Time
+	// 最后一次放回的时间
+	t time.Time
+	// 创建时间，没有设置 MaxLifetime 时为零值
+	created time.Time
 }
 
 // 等到队列中的实例
+// idleConn 为nil表示没有空闲连接但是已经为等待者占用了一个连接名额，由等待者自己新建连接
 type connReq struct {
 	idleConn *idleConn
 }
 
 // channelPool
 type channelPool struct {
+	// 统计计数，包含int64字段，放在首位保证原子操作对齐
+	stats poolStats
 	// 互斥锁
 	mu sync.RWMutex
-	// 连接实例
-	conns chan *idleConn
+	// 空闲连接，队首是最早放回的连接
+	conns []*idleConn
+	// 最大空闲连接数
+	maxIdle int
+	// 空闲连接的复用顺序
+	strategy Strategy
 	// 创建连接方法
 	factory func() (interface{}, error)
 	// 关闭连接方法
@@ -56,44 +121,86 @@ type channelPool struct {
 	ping func(interface{}) error
 	// 连接失效时长
 	idleTimeout time.Duration
+	// 连接最长存活时间
+	maxLifetime time.Duration
+	// 连接检查策略
+	testPolicy TestPolicy
+	// 连接的创建时间，只在设置了 maxLifetime 时记录
+	created map[interface{}]time.Time
+	// 当前在空闲连接池中的连接，用于发现重复归还
+	idle map[interface{}]struct{}
 	// 等待超时时长
 	waitTimeout time.Duration
 	// 最大连接数量
 	maxActive int
-	// 已打开连接的数量
+	// 已打开连接的数量，包括空闲的、借出的以及正在创建的连接
+	// 所有的增减都在持有锁时进行
 	openingConns int
-	// 等待拿到实例队列
+	// 连接池是否已经释放
+	closed bool
+	// 连接池释放并且所有连接都关闭之后被close
+	drained chan struct{}
+	// drained 是否已经被close
+	isDrained bool
+	// 等待拿到实例队列，先到先得
 	connReqs []chan connReq
+	// 最少空闲连接数
+	minIdle int
+	// 后台清理协程
+	reaper *periodic
+}
+
+// validate 检查除 Factory 以外的配置，KeyedPool 的子连接池使用同样的检查
+func (poolConfig *Config) validate() error {
+	if !(poolConfig.InitialCap <= poolConfig.MaxIdle && poolConfig.MaxCap >= poolConfig.MaxIdle && poolConfig.MaxCap > 0 &&
+		poolConfig.InitialCap >= 0 && poolConfig.MaxLifetime >= 0 && poolConfig.MinIdle >= 0 && poolConfig.MinIdle <= poolConfig.MaxIdle) {
+		return errors.New("invalid capacity settings")
+	}
+
+	if poolConfig.Strategy != FIFO && poolConfig.Strategy != LIFO {
+		return errors.New("invalid strategy settings")
+	}
+
+	if poolConfig.Close == nil {
+		return errors.New("invalid close func settings")
+	}
+	return nil
 }
 
 // 初始化连接池
 func NewChannelPool(poolConfig *Config) (Pool, error) {
 	// 配置参数错误
-	if !(poolConfig.InitialCap <= poolConfig.MaxIdle && poolConfig.MaxCap >= poolConfig.MaxIdle &&
-		poolConfig.InitialCap >= 0) {
-		return nil, errors.New("invalid capacity settings")
+	if err := poolConfig.validate(); err != nil {
+		return nil, err
 	}
 
 	if poolConfig.Factory == nil {
 		return nil, errors.New("invalid factory func setting")
 	}
 
-	if poolConfig.Close == nil {
-		return nil, errors.New("invalid close func settings")
-	}
-
 	c := &channelPool{
-		conns:        make(chan *idleConn, poolConfig.MaxIdle),
-		factory:      poolConfig.Factory,
-		close:        poolConfig.Close,
-		idleTimeout:  poolConfig.IdleTimeout,
-		maxActive:    poolConfig.MaxIdle,
-		openingConns: poolConfig.InitialCap,
+		conns:       make([]*idleConn, 0, poolConfig.MaxIdle),
+		maxIdle:     poolConfig.MaxIdle,
+		strategy:    poolConfig.Strategy,
+		factory:     poolConfig.Factory,
+		close:       poolConfig.Close,
+		idleTimeout: poolConfig.IdleTimeout,
+		waitTimeout: poolConfig.WaitTimeout,
+		maxLifetime: poolConfig.MaxLifetime,
+		testPolicy:  poolConfig.TestPolicy,
+		maxActive:   poolConfig.MaxCap,
+		minIdle:     poolConfig.MinIdle,
+		idle:        make(map[interface{}]struct{}),
+		drained:     make(chan struct{}),
+		stats:       poolStats{hook: poolConfig.Hook},
 	}
 
 	if poolConfig.Ping != nil {
 		c.ping = poolConfig.Ping
 	}
+	if c.maxLifetime > 0 {
+		c.created = make(map[interface{}]time.Time)
+	}
 
 	for i := 0; i < poolConfig.InitialCap; i++ {
 		conn, err := c.factory()
@@ -101,84 +208,174 @@ func NewChannelPool(poolConfig *Config) (Pool, error) {
 			c.Release()
 			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
 		}
-		c.conns <- &idleConn{conn: conn, t: time.Now()}
+		c.mu.Lock()
+		c.openingConns++
+		c.pushIdle(c.newIdleConn(conn))
+		c.mu.Unlock()
 	}
-	return c, nil
-}
 
-// getConns 获取所有连接
-func (c *channelPool) getConns() chan *idleConn {
-	// 获取所有连接需要加锁，防止到一半的时候channel被其他channel修改
-	c.mu.Lock()
-	conns := c.conns
-	c.mu.Unlock()
-	return conns
+	if poolConfig.ReapInterval > 0 {
+		c.reaper = startPeriodic(poolConfig.ReapInterval, func() {
+			c.reap()
+			c.fillMinIdle()
+		})
+	}
+	return c, nil
 }
 
-// Get 从pool中取一个连接
+// Get 从pool中取一个连接, 设置了 WaitTimeout 时最多等待 WaitTimeout
 func (c *channelPool) Get() (interface{}, error) {
-	conns := c.getConns()
-	if conns == nil {
-		return nil, ErrClosed
+	ctx := context.Background()
+	if timeout := c.waitTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
+	return c.GetContext(ctx)
+}
+
+// GetContext 从pool中取一个连接，ctx 被取消或者超时的时候放弃等待
+func (c *channelPool) GetContext(ctx context.Context) (interface{}, error) {
 	for {
-		select {
-		case wrapConn := <-conns:
-			// 可能是已经存放空闲连接的channel已经关闭了
-			if wrapConn == nil {
-				return nil, ErrClosed
-			}
-			// 判断是否超时， 超时则丢弃
-			// (重新赋值是为了防止这个变量突然变成小于0的数字，导致下面的时间判断出错)
-			if timeout := c.idleTimeout; timeout > 0 {
-				if wrapConn.t.Add(timeout).Before(time.Now()) {
-					// 已经超时了丢弃并关闭该连接
-					_ = c.Close(wrapConn)
-					continue
-				}
-			}
-			// 判断是否失效，失效则丢弃，如果用户没有设定ping方法，就不检查
-			if err := c.Ping(wrapConn.conn); err != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrClosed
+		}
+
+		// 优先使用空闲连接
+		if wrapConn := c.popIdle(); wrapConn != nil {
+			c.mu.Unlock()
+			// 超时或者失效则丢弃
+			if !c.validate(wrapConn, c.testPolicy.onIdle()) {
 				continue
 			}
 			return wrapConn.conn, nil
-		default:
-			c.mu.Lock()
-			log.Printf("openConn %v %v", c.openingConns, c.maxActive)
-			// 当正在连接的数量大于最大连接数, 加入等待队列中
-			if c.openingConns >= c.maxActive {
-				// 将一个通道传入等待切片中，当前协程监听这个通道，直到得到连接或者超时等
-				// 在有空闲连接的时候会从切片中去找到这个通道，然后往通道加入当前的空闲连接
-				req := make(chan connReq, 1)
-				c.connReqs = append(c.connReqs, req)
-				c.mu.Unlock()
-				ret, ok := <-req
-				if !ok { // 等待队列异常
-					return nil, ErrMaxActiveConnReached
-				}
-				if timeout := c.idleTimeout; timeout > 0 {
-					if ret.idleConn.t.Add(timeout).Before(time.Now()) {
-						// 等到的连接已经超时了，关闭它
-						_ = c.close(ret.idleConn.conn)
-						continue
-					}
-				}
-				return ret.idleConn.conn, nil
-			}
-			// 没有达到最大连接数，新建一个连接
-			if c.factory == nil {
-				c.mu.Unlock()
-				return nil, ErrClosed
-			}
-			conn, err := c.factory()
-			if err != nil {
-				return nil, err
-			}
+		}
+
+		// 没有达到最大连接数，先占用名额再在锁外新建一个连接
+		if c.openingConns < c.maxActive {
 			c.openingConns++
 			c.mu.Unlock()
-			return conn, nil
+			return c.openConn()
+		}
+
+		// 当正在连接的数量大于最大连接数, 加入等待队列中
+		// 将一个通道传入等待切片中，当前协程监听这个通道，直到得到连接或者超时等
+		// 在有空闲连接或者空出名额的时候会按顺序从切片中取出通道，然后往通道写入连接或者名额
+		req := make(chan connReq, 1)
+		c.connReqs = append(c.connReqs, req)
+		c.mu.Unlock()
+		start := time.Now()
+		var ret connReq
+		var ok bool
+		select {
+		case ret, ok = <-req:
+			c.stats.recordWait(time.Since(start))
+		case <-ctx.Done():
+			c.cancelConnReq(req)
+			c.stats.recordWait(time.Since(start))
+			c.stats.recordTimeout()
+			return nil, ctx.Err()
+		}
+		if !ok { // 连接池已经释放，等待队列被关闭
+			return nil, ErrClosed
+		}
+		if ret.idleConn == nil {
+			// 等到的是一个空出来的名额
+			return c.openConn()
+		}
+		// 等到的连接已经超时或者失效了，关闭它
+		if !c.validate(ret.idleConn, c.testPolicy.onBorrow()) {
+			continue
+		}
+		return ret.idleConn.conn, nil
+	}
+}
+
+// openConn 使用已经占用的名额新建连接，失败时释放名额
+func (c *channelPool) openConn() (interface{}, error) {
+	conn, err := c.factory()
+	c.mu.Lock()
+	if err != nil {
+		c.freeSlot()
+		c.mu.Unlock()
+		return nil, err
+	}
+	if c.created != nil && hashable(conn) {
+		c.created[conn] = time.Now()
+	}
+	c.mu.Unlock()
+	if c.testPolicy.onBorrow() {
+		if err := c.Ping(conn); err != nil {
+			_ = c.Close(conn)
+			c.stats.recordPingFailed()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// freeSlot 释放一个连接名额，调用方需要持有锁
+// 有等待者时名额直接交给最早的等待者，保证先到先得
+func (c *channelPool) freeSlot() {
+	if req := c.nextConnReq(); req != nil {
+		req <- connReq{}
+		return
+	}
+	c.openingConns--
+	c.checkDrained()
+}
+
+// checkDrained 连接池释放之后最后一个连接关闭时通知 Shutdown，调用方需要持有锁
+func (c *channelPool) checkDrained() {
+	if c.closed && c.openingConns == 0 && !c.isDrained {
+		c.isDrained = true
+		close(c.drained)
+	}
+}
+
+// nextConnReq 取出最早的等待者，连接池释放后不再分配，调用方需要持有锁
+func (c *channelPool) nextConnReq() chan connReq {
+	l := len(c.connReqs)
+	if c.closed || l == 0 {
+		return nil
+	}
+	req := c.connReqs[0]
+	copy(c.connReqs, c.connReqs[1:])
+	c.connReqs[l-1] = nil
+	c.connReqs = c.connReqs[:l-1]
+	return req
+}
+
+// cancelConnReq 将放弃等待的请求从等待队列中移除
+// 如果已经把连接或者名额交给了这个请求，则重新归还给连接池
+func (c *channelPool) cancelConnReq(req chan connReq) {
+	c.mu.Lock()
+	for i, r := range c.connReqs {
+		if r == req {
+			c.connReqs = append(c.connReqs[:i], c.connReqs[i+1:]...)
+			c.mu.Unlock()
+			return
 		}
 	}
+
+	// 不在等待队列中，说明连接或者名额已经(在锁内)写入了req
+	select {
+	case ret, ok := <-req:
+		if ok && ret.idleConn == nil {
+			c.freeSlot()
+		}
+		c.mu.Unlock()
+		if ok && ret.idleConn != nil {
+			_ = c.putIdle(ret.idleConn)
+		}
+	default:
+		c.mu.Unlock()
+	}
 }
 
 // 连接执行结束，将其归还
@@ -187,52 +384,199 @@ func (c *channelPool) Put(conn interface{}) error {
 		return ErrConnectionIsNil
 	}
 
+	// 先检查重复归还再 Ping，否则 Ping 失败时会关闭一个还留在空闲池里的连接
 	c.mu.Lock()
-
-	if c.conns == nil {
+	if c.isIdle(conn) {
+		// 同一个连接归还了两次
 		c.mu.Unlock()
+		return ErrConnReturned
+	}
+	wrapConn := &idleConn{conn: conn, t: time.Now()}
+	if c.created != nil && hashable(conn) {
+		wrapConn.created = c.created[conn]
+	}
+	c.mu.Unlock()
+
+	if c.testPolicy.onReturn() {
+		// Ping 期间可能有另一个 Put 先归还了同一个连接，putIdle 中还会再检查一次
+		if err := c.Ping(conn); err != nil {
+			c.stats.recordPingFailed()
+			return c.Close(conn)
+		}
+	}
+
+	if c.lifetimeExpired(wrapConn, wrapConn.t) {
+		c.stats.recordLifetimeClosed()
 		return c.Close(conn)
 	}
+	return c.putIdle(wrapConn)
+}
+
+// pushIdle 把连接放到空闲连接池的队尾，调用方需要持有锁并保证没有超过 maxIdle
+func (c *channelPool) pushIdle(wrapConn *idleConn) {
+	c.conns = append(c.conns, wrapConn)
+	c.markIdle(wrapConn.conn)
+}
+
+// popIdle 按复用顺序取出一个空闲连接，没有时返回nil，调用方需要持有锁
+func (c *channelPool) popIdle() *idleConn {
+	n := len(c.conns)
+	if n == 0 {
+		return nil
+	}
+	var wrapConn *idleConn
+	if c.strategy == LIFO {
+		wrapConn = c.conns[n-1]
+		c.conns[n-1] = nil
+		c.conns = c.conns[:n-1]
+	} else {
+		wrapConn = c.conns[0]
+		c.conns[0] = nil
+		c.conns = c.conns[1:]
+	}
+	c.unmarkIdle(wrapConn.conn)
+	return wrapConn
+}
+
+// markIdle 记录连接已经在空闲连接池中，调用方需要持有锁
+// 不能作为map key的连接不做记录
+func (c *channelPool) markIdle(conn interface{}) {
+	if c.idle != nil && hashable(conn) {
+		c.idle[conn] = struct{}{}
+	}
+}
+
+// unmarkIdle 连接已经从空闲连接池中取出，调用方需要持有锁
+func (c *channelPool) unmarkIdle(conn interface{}) {
+	if c.idle != nil && hashable(conn) {
+		delete(c.idle, conn)
+	}
+}
+
+// isIdle 连接是否在空闲连接池中，调用方需要持有锁
+func (c *channelPool) isIdle(conn interface{}) bool {
+	if c.idle == nil || !hashable(conn) {
+		return false
+	}
+	_, ok := c.idle[conn]
+	return ok
+}
+
+// hashable 连接能否作为map的key，不能的连接(例如 []byte)不记录创建时间和空闲状态
+func hashable(conn interface{}) bool {
+	return reflect.TypeOf(conn).Comparable()
+}
+
+// newIdleConn 封装新创建的连接，调用方需要持有锁
+func (c *channelPool) newIdleConn(conn interface{}) *idleConn {
+	now := time.Now()
+	wrapConn := &idleConn{conn: conn, t: now}
+	if c.created != nil {
+		wrapConn.created = now
+		if hashable(conn) {
+			c.created[conn] = now
+		}
+	}
+	return wrapConn
+}
+
+// lifetimeExpired 连接是否超过了最长存活时间
+func (c *channelPool) lifetimeExpired(wrapConn *idleConn, now time.Time) bool {
+	lifetime := c.maxLifetime
+	return lifetime > 0 && !wrapConn.created.IsZero() && wrapConn.created.Add(lifetime).Before(now)
+}
+
+// validate 检查连接是否还能使用，不能使用时关闭连接并返回false
+// ping 为false时只检查时间，不调用 Ping
+func (c *channelPool) validate(wrapConn *idleConn, ping bool) bool {
+	now := time.Now()
+	if timeout := c.idleTimeout; timeout > 0 && wrapConn.t.Add(timeout).Before(now) {
+		_ = c.Close(wrapConn.conn)
+		c.stats.recordIdleClosed()
+		return false
+	}
+	if c.lifetimeExpired(wrapConn, now) {
+		_ = c.Close(wrapConn.conn)
+		c.stats.recordLifetimeClosed()
+		return false
+	}
+	// 如果用户没有设定ping方法，Ping 直接返回nil
+	if ping {
+		if err := c.Ping(wrapConn.conn); err != nil {
+			_ = c.Close(wrapConn.conn)
+			c.stats.recordPingFailed()
+			return false
+		}
+	}
+	return true
+}
+
+// putIdle 将空闲连接交给最早的等待者或者放回空闲连接池，保留连接原有的时间
+func (c *channelPool) putIdle(wrapConn *idleConn) error {
+	c.mu.Lock()
+
+	if c.closed {
+		c.mu.Unlock()
+		return c.Close(wrapConn.conn)
+	}
+
+	if c.isIdle(wrapConn.conn) {
+		c.mu.Unlock()
+		return ErrConnReturned
+	}
 
 	// 等待队列不为空
 	// 等待队列数组中拿出第一个(channel)，将连接写入这个通道中。
-	if l := len(c.connReqs); l > 0 {
-		req := c.connReqs[0]
-		copy(c.connReqs, c.connReqs[1:])
-		c.connReqs = c.connReqs[:l-1]
-		req <- connReq{
-			idleConn: &idleConn{conn: conn, t: time.Now()},
-		}
+	if req := c.nextConnReq(); req != nil {
+		req <- connReq{idleConn: wrapConn}
 		c.mu.Unlock()
 		return nil
-	} else {
-		select {
-		// 保存在空闲连接池
-		case c.conns <- &idleConn{conn: conn, t: time.Now()}:
-			c.mu.Unlock()
-			return nil
-		default: // 空闲连接池满了的话，直接关闭
-			c.mu.Unlock()
-			return c.close(conn)
+	}
 
-		}
+	// 保存在空闲连接池
+	if len(c.conns) < c.maxIdle {
+		c.pushIdle(wrapConn)
+		c.mu.Unlock()
+		return nil
 	}
+
+	// 空闲连接池满了的话，直接关闭
+	c.mu.Unlock()
+	return c.Close(wrapConn.conn)
 }
 
-// Close 关闭链接
+// Close 关闭链接，所有的连接关闭都需要经过这里以维护 openingConns
+// 空出来的名额优先交给等待者，连接池释放之后仍然可以关闭借出的连接
 func (c *channelPool) Close(conn interface{}) error {
 	if conn == nil {
 		return ErrConnectionIsNil
 	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.close == nil {
-		return nil
+	if c.created != nil && hashable(conn) {
+		delete(c.created, conn)
 	}
-	c.openingConns--
+	c.freeSlot()
+	c.mu.Unlock()
 	return c.close(conn)
 }
 
+// closeIdle 关闭最早放回的一个空闲连接，没有空闲连接时返回false
+func (c *channelPool) closeIdle() bool {
+	c.mu.Lock()
+	if len(c.conns) == 0 {
+		c.mu.Unlock()
+		return false
+	}
+	wrapConn := c.conns[0]
+	c.conns[0] = nil
+	c.conns = c.conns[1:]
+	c.unmarkIdle(wrapConn.conn)
+	c.mu.Unlock()
+	_ = c.Close(wrapConn.conn)
+	c.stats.recordIdleClosed()
+	return true
+}
+
 // Ping 检查单挑链接是否有效
 func (c *channelPool) Ping(conn interface{}) error {
 	if conn == nil {
@@ -244,26 +588,68 @@ func (c *channelPool) Ping(conn interface{}) error {
 	return c.ping(conn)
 }
 
+// Release 释放连接池，关闭所有空闲连接并唤醒所有等待者，不等待借出的连接归还
+// 借出的连接在归还时会被关闭
 func (c *channelPool) Release() {
+	c.shutdown()
+}
+
+// Shutdown 优雅地释放连接池：不再借出连接，唤醒所有等待者(返回 ErrClosed)，
+// 然后等待借出的连接全部归还并关闭，ctx 结束时返回 ctx.Err()
+func (c *channelPool) Shutdown(ctx context.Context) error {
+	drained := c.shutdown()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown 标记连接池已经释放并关闭空闲连接，返回所有连接都关闭时会被close的通道
+func (c *channelPool) shutdown() <-chan struct{} {
+	// 先停止后台清理协程，避免它持有的连接在关闭后被放回
+	c.reaper.Stop()
+
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return c.drained
+	}
 	conns := c.conns
 	c.conns = nil
-	c.factory = nil
-	c.ping = nil
-	closeFun := c.close
-	c.close = nil
-	c.mu.Unlock()
-
-	if conns == nil {
-		return
+	c.closed = true
+	c.idle = nil
+	// 唤醒所有等待者
+	for _, req := range c.connReqs {
+		close(req)
 	}
+	c.connReqs = nil
+	c.checkDrained()
+	c.mu.Unlock()
 
-	close(conns)
-	for wrapconn := range conns {
-		_ = closeFun(wrapconn.conn)
+	for _, wrapconn := range conns {
+		_ = c.Close(wrapconn.conn)
 	}
+	return c.drained
 }
 
 func (c *channelPool) Len() int {
-	return len(c.getConns())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.conns)
+}
+
+// Stats 返回连接池当前状态的快照
+func (c *channelPool) Stats() Stats {
+	c.mu.Lock()
+	st := Stats{
+		OpenConns: c.openingConns,
+		IdleConns: len(c.conns),
+		Waiters:   len(c.connReqs),
+	}
+	c.mu.Unlock()
+	st.InUseConns = st.OpenConns - st.IdleConns
+	c.stats.fill(&st)
+	return st
 }
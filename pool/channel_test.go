@@ -0,0 +1,254 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeConn struct {
+	id int64
+}
+
+// fakeFactory 统计创建和关闭次数的 Factory/Close，可以按比例模拟失败
+type fakeFactory struct {
+	created int64
+	closed  int64
+	// 创建连接失败的概率为 1/failEvery，0 表示不失败
+	failEvery int
+}
+
+func (f *fakeFactory) Factory() (interface{}, error) {
+	if f.failEvery > 0 && rand.Intn(f.failEvery) == 0 {
+		return nil, errors.New("factory failed")
+	}
+	return &fakeConn{id: atomic.AddInt64(&f.created, 1)}, nil
+}
+
+func (f *fakeFactory) Close(interface{}) error {
+	atomic.AddInt64(&f.closed, 1)
+	return nil
+}
+
+// open 当前没有关闭的连接数
+func (f *fakeFactory) open() int64 {
+	return atomic.LoadInt64(&f.created) - atomic.LoadInt64(&f.closed)
+}
+
+func TestChannelPoolStress(t *testing.T) {
+	const maxCap = 4
+	for _, tc := range []struct {
+		name     string
+		strategy Strategy
+	}{{"FIFO", FIFO}, {"LIFO", LIFO}} {
+		strategy := tc.strategy
+		t.Run(tc.name, func(t *testing.T) {
+			f := &fakeFactory{failEvery: 10}
+			p, err := NewChannelPool(&Config{
+				MaxIdle:  3,
+				MaxCap:   maxCap,
+				Strategy: strategy,
+				Factory:  f.Factory,
+				Close:    f.Close,
+				Ping: func(interface{}) error {
+					if rand.Intn(20) == 0 {
+						return errors.New("ping failed")
+					}
+					return nil
+				},
+				IdleTimeout:  5 * time.Millisecond,
+				MaxLifetime:  20 * time.Millisecond,
+				ReapInterval: time.Millisecond,
+				MinIdle:      1,
+				TestPolicy:   TestAlways,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						timeout := time.Duration(rand.Intn(3)+1) * time.Millisecond
+						ctx, cancel := context.WithTimeout(context.Background(), timeout)
+						conn, err := p.GetContext(ctx)
+						cancel()
+						if err != nil {
+							continue
+						}
+						if open := p.Stats().OpenConns; open > maxCap || open <= 0 {
+							t.Errorf("Stats.OpenConns = %d with a borrowed conn, MaxCap %d", open, maxCap)
+						}
+						time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+						if rand.Intn(5) == 0 {
+							_ = p.Close(conn)
+						} else {
+							_ = p.Put(conn)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			// 停止后台清理协程之后再比较，避免统计期间有连接被创建或者关闭
+			p.(*channelPool).reaper.Stop()
+			st := p.Stats()
+			if int64(st.OpenConns) != f.open() {
+				t.Errorf("Stats.OpenConns = %d, created-closed = %d", st.OpenConns, f.open())
+			}
+			if st.OpenConns != st.IdleConns || st.InUseConns != 0 || st.Waiters != 0 {
+				t.Errorf("unexpected stats after all conns returned: %+v", st)
+			}
+
+			p.Release()
+			if st := p.Stats(); st.OpenConns != 0 || f.open() != 0 {
+				t.Errorf("after Release: %+v, created=%d closed=%d", st, f.created, f.closed)
+			}
+		})
+	}
+}
+
+func TestChannelPoolSlotHandoff(t *testing.T) {
+	f := &fakeFactory{}
+	p, err := NewChannelPool(&Config{MaxIdle: 2, MaxCap: 2, Factory: f.Factory, Close: f.Close})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	a, _ := p.Get()
+	b, _ := p.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext on full pool = %v, want DeadlineExceeded", err)
+	}
+
+	// 关闭连接空出的名额交给等待者新建连接
+	go func() {
+		time.Sleep(2 * time.Millisecond)
+		_ = p.Close(a)
+	}()
+	c, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := c.(*fakeConn).id; id != 3 {
+		t.Fatalf("got conn %d, want a new conn 3", id)
+	}
+
+	_ = p.Put(b)
+	if err := p.Put(b); err != ErrConnReturned {
+		t.Fatalf("second Put = %v, want ErrConnReturned", err)
+	}
+	_ = p.Put(c)
+	if open := f.open(); open != 2 || p.Len() != 2 {
+		t.Fatalf("open=%d idle=%d, want 2 and 2", open, p.Len())
+	}
+}
+
+func TestChannelPoolShutdown(t *testing.T) {
+	f := &fakeFactory{}
+	p, err := NewChannelPool(&Config{InitialCap: 1, MaxIdle: 2, MaxCap: 2, Factory: f.Factory, Close: f.Close})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _ := p.Get()
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		_ = p.Put(conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if f.open() != 0 {
+		t.Fatalf("created=%d closed=%d after Shutdown", f.created, f.closed)
+	}
+	if _, err := p.Get(); err != ErrClosed {
+		t.Fatalf("Get after Shutdown = %v, want ErrClosed", err)
+	}
+}
+
+func TestChannelPoolUnhashableConn(t *testing.T) {
+	// []byte 不能作为map的key，设置了 MaxLifetime 也不能panic
+	p, err := NewChannelPool(&Config{
+		InitialCap:  1,
+		MaxIdle:     2,
+		MaxCap:      2,
+		MaxLifetime: time.Second,
+		TestPolicy:  TestAlways,
+		Factory:     func() (interface{}, error) { return []byte("conn"), nil },
+		Close:       func(interface{}) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(b); err != nil {
+		t.Fatal(err)
+	}
+	if st := p.Stats(); st.OpenConns != 1 || st.IdleConns != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestChannelPoolDoublePutFailingPing(t *testing.T) {
+	f := &fakeFactory{}
+	var pingErr atomic.Value
+	pingErr.Store(false)
+	p, err := NewChannelPool(&Config{
+		MaxIdle:    2,
+		MaxCap:     2,
+		TestPolicy: TestOnReturn,
+		Factory:    f.Factory,
+		Close:      f.Close,
+		Ping: func(interface{}) error {
+			if pingErr.Load().(bool) {
+				return errors.New("ping failed")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	conn, _ := p.Get()
+	if err := p.Put(conn); err != nil {
+		t.Fatal(err)
+	}
+	// 重复归还在 Ping 之前就被拒绝，不能关闭还在空闲池里的连接
+	pingErr.Store(true)
+	if err := p.Put(conn); err != ErrConnReturned {
+		t.Fatalf("second Put = %v, want ErrConnReturned", err)
+	}
+	if st := p.Stats(); st.OpenConns != 1 || st.IdleConns != 1 || st.InUseConns != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if f.open() != 1 {
+		t.Fatalf("created=%d closed=%d, want the idle conn kept open", f.created, f.closed)
+	}
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+var (
+	ErrNotNetConn = errors.New("connection is not a net.Conn")
+)
+
+// PoolConn 包装从连接池中取出的 net.Conn
+// 调用 Close 会把连接归还给连接池而不是关闭它
+type PoolConn struct {
+	net.Conn
+	mu sync.Mutex
+	p  Pool
+	// 标记连接不可用，Close 时直接关闭
+	unusable bool
+	// 已经归还过了
+	returned bool
+}
+
+// NewPoolConn 包装一个从 p 中取出的连接
+func NewPoolConn(p Pool, conn net.Conn) *PoolConn {
+	return &PoolConn{Conn: conn, p: p}
+}
+
+// GetConn 从连接池中取一个连接并包装为 PoolConn, 连接池的 Factory 需要返回 net.Conn
+func GetConn(p Pool) (*PoolConn, error) {
+	return wrapConn(p, p.Get)
+}
+
+// GetConnContext 和 GetConn 一样，等待时可以通过ctx取消或者设置超时
+func GetConnContext(ctx context.Context, p Pool) (*PoolConn, error) {
+	return wrapConn(p, func() (interface{}, error) {
+		return p.GetContext(ctx)
+	})
+}
+
+func wrapConn(p Pool, get func() (interface{}, error)) (*PoolConn, error) {
+	conn, err := get()
+	if err != nil {
+		return nil, err
+	}
+	netConn, ok := conn.(net.Conn)
+	if !ok {
+		_ = p.Put(conn)
+		return nil, ErrNotNetConn
+	}
+	return NewPoolConn(p, netConn), nil
+}
+
+// Close 将连接归还给连接池，被 MarkUnusable 标记过的连接会被关闭
+// 重复调用返回 ErrConnReturned
+func (pc *PoolConn) Close() error {
+	pc.mu.Lock()
+	if pc.returned {
+		pc.mu.Unlock()
+		return ErrConnReturned
+	}
+	pc.returned = true
+	unusable := pc.unusable
+	pc.mu.Unlock()
+
+	if unusable {
+		return pc.p.Close(pc.Conn)
+	}
+	return pc.p.Put(pc.Conn)
+}
+
+// MarkUnusable 标记连接不可用，下一次 Close 会真正关闭连接
+func (pc *PoolConn) MarkUnusable() {
+	pc.mu.Lock()
+	pc.unusable = true
+	pc.mu.Unlock()
+}
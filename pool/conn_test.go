@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// newPipePool 返回一个 Factory 创建 net.Pipe 连接的连接池，并统计创建和关闭次数
+func newPipePool(t *testing.T) (Pool, *fakeFactory) {
+	t.Helper()
+	f := &fakeFactory{}
+	p, err := NewChannelPool(&Config{
+		MaxIdle: 2,
+		MaxCap:  2,
+		Factory: func() (interface{}, error) {
+			atomic.AddInt64(&f.created, 1)
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		},
+		Close: func(conn interface{}) error {
+			atomic.AddInt64(&f.closed, 1)
+			return conn.(net.Conn).Close()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, f
+}
+
+func TestPoolConnClose(t *testing.T) {
+	p, f := newPipePool(t)
+	defer p.Release()
+
+	pc, err := GetConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Close 归还连接而不是关闭
+	if err := pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 1 || f.open() != 1 {
+		t.Fatalf("idle=%d open=%d after Close, want 1 and 1", p.Len(), f.open())
+	}
+	if err := pc.Close(); err != ErrConnReturned {
+		t.Fatalf("second Close = %v, want ErrConnReturned", err)
+	}
+
+	// 再次取出的是同一个连接
+	again, err := GetConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Conn != pc.Conn {
+		t.Fatal("GetConn did not reuse the returned conn")
+	}
+	_ = again.Close()
+}
+
+func TestPoolConnMarkUnusable(t *testing.T) {
+	p, f := newPipePool(t)
+	defer p.Release()
+
+	pc, err := GetConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.MarkUnusable()
+	if err := pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 0 || f.open() != 0 {
+		t.Fatalf("idle=%d open=%d after closing an unusable conn, want 0 and 0", p.Len(), f.open())
+	}
+	if st := p.Stats(); st.OpenConns != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestGetConnNotNetConn(t *testing.T) {
+	f := &fakeFactory{}
+	p, err := NewChannelPool(&Config{MaxIdle: 1, MaxCap: 1, Factory: f.Factory, Close: f.Close})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	if _, err := GetConn(p); err != ErrNotNetConn {
+		t.Fatalf("GetConn = %v, want ErrNotNetConn", err)
+	}
+	// 取出的连接要还回去，不能占着名额
+	if p.Len() != 1 || f.open() != 1 {
+		t.Fatalf("idle=%d open=%d, want the conn returned to the pool", p.Len(), f.open())
+	}
+}
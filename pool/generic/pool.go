@@ -0,0 +1,147 @@
+package generic
+
+import (
+	"context"
+	"time"
+
+	"github.com/limitzhang87/go-utils/pool"
+)
+
+// Pool 类型安全的连接池，语义和 pool.Pool 一致
+type Pool[T any] interface {
+	Get() (T, error)
+	// GetContext 获取连接，等待时可以通过ctx取消或者设置超时
+	GetContext(ctx context.Context) (T, error)
+	Put(T) error
+	Close(T) error
+	Release()
+	// Shutdown 不再借出连接并唤醒所有等待者，等待借出的连接全部归还后关闭所有连接
+	Shutdown(ctx context.Context) error
+	Len() int
+	// Stats 返回连接池当前状态的快照
+	Stats() pool.Stats
+}
+
+// Config 连接池相关配置，字段含义与 pool.Config 相同
+type Config[T any] struct {
+	// 连接池中拥有的最小连接数
+	InitialCap int
+	// 最大并发存活连接数
+	MaxCap int
+	// 最大空闲连接
+	MaxIdle int
+	// 生成连接的方法
+	Factory func() (T, error)
+	// 关闭链接的方法
+	Close func(T) error
+	// 检查连接是否有效的方法
+	Ping func(T) error
+	// 连接最大空闲时间， 超过改时间则将失效
+	IdleTimeout time.Duration
+	// Get 时等待空闲连接的最长时间，0表示一直等待
+	WaitTimeout time.Duration
+	// 连接从创建开始的最长存活时间，0表示不限制
+	MaxLifetime time.Duration
+	// 什么时候调用 Ping 检查连接，默认只检查从空闲连接池取出的连接
+	TestPolicy pool.TestPolicy
+	// 后台清理空闲连接的间隔，0表示不启动后台清理
+	ReapInterval time.Duration
+	// 后台清理时保持的最少空闲连接数，需要 ReapInterval > 0 才生效
+	MinIdle int
+	// 空闲连接的复用顺序，默认 FIFO
+	Strategy pool.Strategy
+	// 连接池事件回调，可以为nil
+	Hook pool.StatsHook
+}
+
+// channelPool 基于 pool.channelPool 实现，只负责类型转换
+type channelPool[T any] struct {
+	p pool.Pool
+}
+
+// NewChannelPool 初始化连接池
+func NewChannelPool[T any](poolConfig *Config[T]) (Pool[T], error) {
+	cfg := &pool.Config{
+		InitialCap:   poolConfig.InitialCap,
+		MaxCap:       poolConfig.MaxCap,
+		MaxIdle:      poolConfig.MaxIdle,
+		IdleTimeout:  poolConfig.IdleTimeout,
+		WaitTimeout:  poolConfig.WaitTimeout,
+		MaxLifetime:  poolConfig.MaxLifetime,
+		TestPolicy:   poolConfig.TestPolicy,
+		ReapInterval: poolConfig.ReapInterval,
+		MinIdle:      poolConfig.MinIdle,
+		Strategy:     poolConfig.Strategy,
+		Hook:         poolConfig.Hook,
+	}
+	// 未设置的方法保持为nil，交给 pool.NewChannelPool 做参数校验
+	if factory := poolConfig.Factory; factory != nil {
+		cfg.Factory = func() (interface{}, error) {
+			return factory()
+		}
+	}
+	if closeFunc := poolConfig.Close; closeFunc != nil {
+		cfg.Close = func(conn interface{}) error {
+			return closeFunc(conn.(T))
+		}
+	}
+	if ping := poolConfig.Ping; ping != nil {
+		cfg.Ping = func(conn interface{}) error {
+			return ping(conn.(T))
+		}
+	}
+
+	p, err := pool.NewChannelPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &channelPool[T]{p: p}, nil
+}
+
+// Get 从pool中取一个连接
+func (c *channelPool[T]) Get() (T, error) {
+	return c.cast(c.p.Get())
+}
+
+// GetContext 从pool中取一个连接，ctx 被取消或者超时的时候放弃等待
+func (c *channelPool[T]) GetContext(ctx context.Context) (T, error) {
+	return c.cast(c.p.GetContext(ctx))
+}
+
+// Put 连接执行结束，将其归还
+func (c *channelPool[T]) Put(conn T) error {
+	return c.p.Put(conn)
+}
+
+// Close 关闭链接
+func (c *channelPool[T]) Close(conn T) error {
+	return c.p.Close(conn)
+}
+
+// Release 释放连接池
+func (c *channelPool[T]) Release() {
+	c.p.Release()
+}
+
+// Shutdown 优雅地释放连接池
+func (c *channelPool[T]) Shutdown(ctx context.Context) error {
+	return c.p.Shutdown(ctx)
+}
+
+// Len 空闲连接数量
+func (c *channelPool[T]) Len() int {
+	return c.p.Len()
+}
+
+// Stats 返回连接池当前状态的快照
+func (c *channelPool[T]) Stats() pool.Stats {
+	return c.p.Stats()
+}
+
+func (c *channelPool[T]) cast(conn interface{}, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return conn.(T), nil
+}
@@ -0,0 +1,417 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// KeyedPool 按key(例如后端地址)划分的连接池，每个key对应一个子连接池
+// Get/Put/Close/Release 的语义和 Pool 一致
+type KeyedPool interface {
+	Get(key string) (interface{}, error)
+	// GetContext 获取连接，等待时可以通过ctx取消或者设置超时
+	GetContext(ctx context.Context, key string) (interface{}, error)
+	Put(key string, conn interface{}) error
+	Close(key string, conn interface{}) error
+	Release()
+	// Shutdown 优雅地释放所有子连接池，语义和 Pool.Shutdown 一致
+	Shutdown(ctx context.Context) error
+	// Len 所有子连接池的空闲连接数之和
+	Len() int
+}
+
+// KeyedConfig 按key划分的连接池配置
+type KeyedConfig struct {
+	// 每个子连接池的配置，MaxCap 为单个key的最大连接数
+	// Factory 字段会被忽略，使用 KeyFactory 创建连接
+	Config
+	// 根据key生成连接的方法
+	KeyFactory func(key string) (interface{}, error)
+	// 所有key加起来的最大连接数，0表示不限制
+	// 达到上限时 Get 先关闭其他key的空闲连接，没有空闲连接时和 Pool 一样等待
+	MaxTotal int
+	// 子连接池没有任何连接多久之后被回收，0表示不回收
+	EvictTimeout time.Duration
+}
+
+// keyedEntry 子连接池以及它的使用情况
+type keyedEntry struct {
+	pool Pool
+	// 正在使用该子连接池的调用数，大于0时不会被回收
+	refs int
+	// 最后一次使用时间
+	lastUsed time.Time
+	// 等待者已经为这个key取得、还没有被创建连接用掉的全局名额
+	prepaid int
+}
+
+// keyedPool
+type keyedPool struct {
+	mu  sync.Mutex
+	cfg KeyedConfig
+	// 子连接池
+	pools map[string]*keyedEntry
+	// 全局连接数信号量，为nil时不限制
+	total chan struct{}
+	// 正在等待全局名额的 Get 数量
+	totalWaiters int
+	// 是否已经释放
+	closed bool
+	// 回收空子连接池的协程
+	evictor *periodic
+}
+
+// NewKeyedPool 初始化按key划分的连接池，子连接池在第一次使用时创建
+func NewKeyedPool(poolConfig *KeyedConfig) (KeyedPool, error) {
+	if poolConfig.KeyFactory == nil {
+		return nil, errors.New("invalid factory func setting")
+	}
+	// 子连接池在第一次使用时才创建，配置错误要在这里发现
+	if err := poolConfig.Config.validate(); err != nil {
+		return nil, err
+	}
+	if poolConfig.MaxTotal < 0 || poolConfig.EvictTimeout < 0 {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	k := &keyedPool{
+		cfg:   *poolConfig,
+		pools: make(map[string]*keyedEntry),
+	}
+	if poolConfig.MaxTotal > 0 {
+		k.total = make(chan struct{}, poolConfig.MaxTotal)
+	}
+	if timeout := poolConfig.EvictTimeout; timeout > 0 {
+		k.evictor = startPeriodic(checkInterval(timeout), func() {
+			k.evict(timeout)
+		})
+	}
+	return k, nil
+}
+
+// newSubPool 创建key对应的子连接池，创建和关闭连接时维护全局连接数
+func (k *keyedPool) newSubPool(key string, entry *keyedEntry) (Pool, error) {
+	cfg := k.cfg.Config
+	keyFactory := k.cfg.KeyFactory
+	closeFunc := k.cfg.Close
+	total := k.total
+
+	cfg.Factory = func() (interface{}, error) {
+		if total != nil && !k.takePrepaid(entry) {
+			// 这里拿不到调用方的ctx，不能阻塞，由 GetContext 等待名额之后重试
+			select {
+			case total <- struct{}{}:
+			default:
+				return nil, ErrMaxActiveConnReached
+			}
+		}
+		conn, err := keyFactory(key)
+		if err != nil && total != nil {
+			<-total
+		}
+		return conn, err
+	}
+	cfg.Close = func(conn interface{}) error {
+		err := closeFunc(conn)
+		if total != nil {
+			<-total
+		}
+		return err
+	}
+	return NewChannelPool(&cfg)
+}
+
+// acquire 取得key对应的子连接池并增加引用，create 为true时不存在则创建
+// 连接池释放后不再借出连接，但是借出的连接仍然要归还给原来的子连接池
+func (k *keyedPool) acquire(key string, create bool) (*keyedEntry, error) {
+	k.mu.Lock()
+	if k.closed && create {
+		k.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if entry, ok := k.pools[key]; ok {
+		k.ref(entry)
+		k.mu.Unlock()
+		return entry, nil
+	}
+	k.mu.Unlock()
+	if !create {
+		return nil, nil
+	}
+
+	// 在锁外创建子连接池，InitialCap > 0 时会调用 KeyFactory 建立连接，
+	// 而且创建连接时会再次获取 k.mu
+	entry := &keyedEntry{}
+	p, err := k.newSubPool(key, entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.pool = p
+
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		p.Release()
+		return nil, ErrClosed
+	}
+	if existing, ok := k.pools[key]; ok {
+		// 另一个调用先创建好了，丢弃自己创建的子连接池
+		k.ref(existing)
+		k.mu.Unlock()
+		p.Release()
+		return existing, nil
+	}
+	k.pools[key] = entry
+	k.ref(entry)
+	k.mu.Unlock()
+	return entry, nil
+}
+
+// ref 增加子连接池的引用，调用方需要持有锁
+func (k *keyedPool) ref(entry *keyedEntry) {
+	entry.refs++
+	entry.lastUsed = time.Now()
+}
+
+// release 减少子连接池的引用
+func (k *keyedPool) release(entry *keyedEntry) {
+	k.mu.Lock()
+	entry.refs--
+	entry.lastUsed = time.Now()
+	k.mu.Unlock()
+}
+
+// Get 从key对应的子连接池中取一个连接, 设置了 WaitTimeout 时最多等待 WaitTimeout
+func (k *keyedPool) Get(key string) (interface{}, error) {
+	ctx := context.Background()
+	if timeout := k.cfg.WaitTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return k.GetContext(ctx, key)
+}
+
+// GetContext 从key对应的子连接池中取一个连接，ctx 被取消或者超时的时候放弃等待
+func (k *keyedPool) GetContext(ctx context.Context, key string) (interface{}, error) {
+	entry, err := k.acquire(key, true)
+	if err != nil {
+		return nil, err
+	}
+	defer k.release(entry)
+	// 本次调用取得的全局名额数量
+	prepaid := 0
+	for {
+		conn, err := entry.pool.GetContext(ctx)
+		k.returnPrepaid(entry, prepaid)
+		prepaid = 0
+		if err != ErrMaxActiveConnReached || k.total == nil {
+			return conn, err
+		}
+		// 全局连接数已满，先关闭其他key的一个空闲连接腾出名额
+		if k.closeIdle(key) {
+			continue
+		}
+		// 没有可以关闭的空闲连接，等待借出的连接归还或者关闭
+		if err := k.waitTotal(ctx, entry); err != nil {
+			return nil, err
+		}
+		prepaid++
+	}
+}
+
+// waitTotal 等待一个全局名额，取得的名额记在 entry 上留给创建连接时使用
+func (k *keyedPool) waitTotal(ctx context.Context, entry *keyedEntry) error {
+	k.mu.Lock()
+	k.totalWaiters++
+	k.mu.Unlock()
+	defer func() {
+		k.mu.Lock()
+		k.totalWaiters--
+		k.mu.Unlock()
+	}()
+
+	select {
+	case k.total <- struct{}{}:
+		k.mu.Lock()
+		entry.prepaid++
+		k.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takePrepaid 使用一个已经取得的全局名额
+func (k *keyedPool) takePrepaid(entry *keyedEntry) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if entry.prepaid == 0 {
+		return false
+	}
+	entry.prepaid--
+	return true
+}
+
+// returnPrepaid 归还本次调用取得的、没有被用掉的全局名额，例如等到名额之后拿到的是空闲连接
+func (k *keyedPool) returnPrepaid(entry *keyedEntry, prepaid int) {
+	if prepaid == 0 {
+		return
+	}
+	k.mu.Lock()
+	n := entry.prepaid
+	if n > prepaid {
+		n = prepaid
+	}
+	entry.prepaid -= n
+	k.mu.Unlock()
+	for i := 0; i < n; i++ {
+		<-k.total
+	}
+}
+
+// closeIdle 关闭除 key 以外的子连接池中的一个空闲连接，释放它占用的全局名额
+func (k *keyedPool) closeIdle(key string) bool {
+	k.mu.Lock()
+	pools := make([]*channelPool, 0, len(k.pools))
+	for other, entry := range k.pools {
+		if other == key {
+			continue
+		}
+		if p, ok := entry.pool.(*channelPool); ok {
+			pools = append(pools, p)
+		}
+	}
+	k.mu.Unlock()
+
+	for _, p := range pools {
+		if p.closeIdle() {
+			return true
+		}
+	}
+	return false
+}
+
+// Put 将连接归还给key对应的子连接池
+func (k *keyedPool) Put(key string, conn interface{}) error {
+	if conn == nil {
+		return ErrConnectionIsNil
+	}
+	entry, err := k.acquire(key, false)
+	if err != nil || entry == nil {
+		// 不是从这个连接池取得的连接，直接关闭
+		return k.closeConn(conn)
+	}
+	defer k.release(entry)
+	if k.hasTotalWaiters() {
+		// 有 Get 在等待全局名额，连接留着空闲只会让它们一直等下去
+		return entry.pool.Close(conn)
+	}
+	return entry.pool.Put(conn)
+}
+
+func (k *keyedPool) hasTotalWaiters() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.totalWaiters > 0
+}
+
+// Close 关闭key对应子连接池中的连接
+func (k *keyedPool) Close(key string, conn interface{}) error {
+	if conn == nil {
+		return ErrConnectionIsNil
+	}
+	entry, err := k.acquire(key, false)
+	if err != nil || entry == nil {
+		return k.closeConn(conn)
+	}
+	defer k.release(entry)
+	return entry.pool.Close(conn)
+}
+
+// closeConn 关闭不属于任何子连接池的连接
+func (k *keyedPool) closeConn(conn interface{}) error {
+	err := k.cfg.Close(conn)
+	if k.total != nil {
+		select {
+		case <-k.total:
+		default:
+		}
+	}
+	return err
+}
+
+// Release 释放所有子连接池
+func (k *keyedPool) Release() {
+	for _, p := range k.detach() {
+		p.Release()
+	}
+}
+
+// Shutdown 优雅地释放所有子连接池
+func (k *keyedPool) Shutdown(ctx context.Context) error {
+	pools := k.detach()
+	// 先让所有子连接池停止借出并唤醒等待者，再逐个等待
+	for _, p := range pools {
+		p.Release()
+	}
+	for _, p := range pools {
+		if err := p.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detach 标记连接池已经释放并返回所有子连接池
+// 子连接池仍然保留在map中，用来接收借出连接的归还
+func (k *keyedPool) detach() []Pool {
+	k.evictor.Stop()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closed {
+		return nil
+	}
+	k.closed = true
+	pools := make([]Pool, 0, len(k.pools))
+	for _, entry := range k.pools {
+		pools = append(pools, entry.pool)
+	}
+	return pools
+}
+
+// Len 所有子连接池的空闲连接数之和
+func (k *keyedPool) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n := 0
+	for _, entry := range k.pools {
+		n += entry.pool.Len()
+	}
+	return n
+}
+
+// evict 回收超过 timeout 没有任何连接也没有被使用的子连接池
+func (k *keyedPool) evict(timeout time.Duration) {
+	now := time.Now()
+	var evicted []Pool
+	k.mu.Lock()
+	for key, entry := range k.pools {
+		if entry.refs > 0 || entry.lastUsed.Add(timeout).After(now) {
+			continue
+		}
+		if st := entry.pool.Stats(); st.OpenConns > 0 || st.Waiters > 0 {
+			continue
+		}
+		delete(k.pools, key)
+		evicted = append(evicted, entry.pool)
+	}
+	k.mu.Unlock()
+
+	for _, p := range evicted {
+		p.Release()
+	}
+}
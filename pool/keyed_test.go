@@ -0,0 +1,199 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestKeyedPool(t *testing.T, f *fakeFactory, cfg KeyedConfig) KeyedPool {
+	t.Helper()
+	cfg.KeyFactory = func(string) (interface{}, error) { return f.Factory() }
+	cfg.Close = f.Close
+	k, err := NewKeyedPool(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyedPoolInvalidConfig(t *testing.T) {
+	f := &fakeFactory{}
+	_, err := NewKeyedPool(&KeyedConfig{
+		Config:     Config{Close: f.Close},
+		KeyFactory: func(string) (interface{}, error) { return f.Factory() },
+	})
+	if err == nil {
+		t.Fatal("NewKeyedPool accepted MaxCap 0")
+	}
+}
+
+func TestKeyedPoolInitialCap(t *testing.T) {
+	f := &fakeFactory{}
+	k := newTestKeyedPool(t, f, KeyedConfig{
+		Config:   Config{InitialCap: 1, MaxIdle: 1, MaxCap: 2},
+		MaxTotal: 4,
+	})
+	defer k.Release()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := k.Get("a")
+		if err == nil {
+			err = k.Put("a", conn)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first Get of a key with InitialCap > 0 deadlocked")
+	}
+	if open := f.open(); open != 1 {
+		t.Fatalf("open conns = %d, want 1", open)
+	}
+}
+
+func TestKeyedPoolMaxTotalReclaimsIdle(t *testing.T) {
+	f := &fakeFactory{}
+	k := newTestKeyedPool(t, f, KeyedConfig{
+		Config:   Config{MaxIdle: 2, MaxCap: 2, WaitTimeout: 200 * time.Millisecond},
+		MaxTotal: 2,
+	})
+	defer k.Release()
+
+	a1, _ := k.Get("a")
+	a2, _ := k.Get("a")
+	_ = k.Put("a", a1)
+	_ = k.Put("a", a2)
+
+	// 全局名额都被 "a" 的空闲连接占着，关闭其中一个给 "b"
+	start := time.Now()
+	b, err := k.Get("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("Get waited %v although an idle conn could be closed", d)
+	}
+	if open := f.open(); open != 2 {
+		t.Fatalf("open conns = %d, want 2", open)
+	}
+	_ = k.Put("b", b)
+}
+
+func TestKeyedPoolMaxTotalWait(t *testing.T) {
+	f := &fakeFactory{}
+	k := newTestKeyedPool(t, f, KeyedConfig{
+		Config:   Config{MaxIdle: 2, MaxCap: 2},
+		MaxTotal: 2,
+	})
+	defer k.Release()
+
+	a1, _ := k.Get("a")
+	a2, _ := k.Get("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := k.GetContext(ctx, "b"); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext with all slots borrowed = %v, want DeadlineExceeded", err)
+	}
+
+	// 借出的连接归还时名额交给等待的 "b"
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		_ = k.Put("a", a1)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	b, err := k.GetContext(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open := f.open(); open > 2 {
+		t.Fatalf("open conns = %d exceeds MaxTotal", open)
+	}
+	_ = k.Put("b", b)
+	_ = k.Put("a", a2)
+}
+
+func TestKeyedPoolEvict(t *testing.T) {
+	f := &fakeFactory{}
+	k := newTestKeyedPool(t, f, KeyedConfig{
+		Config:       Config{MaxIdle: 1, MaxCap: 1},
+		EvictTimeout: 5 * time.Millisecond,
+	})
+	defer k.Release()
+
+	conn, _ := k.Get("a")
+	_ = k.Close("a", conn)
+	held, _ := k.Get("b")
+
+	kp := k.(*keyedPool)
+	deadline := time.Now().Add(time.Second)
+	for {
+		kp.mu.Lock()
+		_, hasA := kp.pools["a"]
+		_, hasB := kp.pools["b"]
+		kp.mu.Unlock()
+		if !hasB {
+			t.Fatal("sub-pool with a borrowed conn was evicted")
+		}
+		if !hasA {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("empty sub-pool was not evicted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_ = k.Put("b", held)
+}
+
+func TestKeyedPoolStress(t *testing.T) {
+	const maxTotal = 3
+	f := &fakeFactory{failEvery: 10}
+	k := newTestKeyedPool(t, f, KeyedConfig{
+		Config:       Config{InitialCap: 1, MaxIdle: 2, MaxCap: 2, WaitTimeout: 2 * time.Millisecond},
+		MaxTotal:     maxTotal,
+		EvictTimeout: time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprint(rand.Intn(4))
+				conn, err := k.Get(key)
+				if err != nil {
+					continue
+				}
+				if open := f.open(); open > maxTotal {
+					t.Errorf("open conns %d exceeds MaxTotal %d", open, maxTotal)
+				}
+				if rand.Intn(5) == 0 {
+					_ = k.Close(key, conn)
+				} else {
+					_ = k.Put(key, conn)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	k.Release()
+	if f.open() != 0 {
+		t.Fatalf("created=%d closed=%d after Release", f.created, f.closed)
+	}
+	if n := len(k.(*keyedPool).total); n != 0 {
+		t.Fatalf("%d global slots still taken after Release", n)
+	}
+}
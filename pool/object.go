@@ -0,0 +1,297 @@
+package pool
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ObjectConfig 对象池相关配置
+// 重复归还检测和泄漏检测需要区分不同的对象，只对指针、chan 类型(或者动态类型是它们的接口)生效，
+// []byte 之类的值类型对象不会被记录，需要检测时请使用 *[]byte、*bytes.Buffer 等指针类型
+// 开启检测后借出的对象在归还或者 Discard 之前一直被对象池引用，借出后直接丢弃的对象不会被回收
+type ObjectConfig[T any] struct {
+	// 创建对象的方法
+	New func() (T, error)
+	// 借出前调用，返回错误时丢弃该对象，可以为nil
+	OnBorrow func(T) error
+	// 归还时调用，用来重置对象，返回错误时丢弃该对象，可以为nil
+	OnReturn func(T) error
+	// 丢弃对象时调用，可以为nil
+	Destroy func(T)
+	// 最多保留的空闲对象数
+	MaxIdle int
+	// 计算对象大小的方法，和 MaxSize 一起使用，可以为nil
+	Size func(T) int
+	// 归还时大小超过 MaxSize 的对象不再复用，0表示不限制
+	MaxSize int
+	// 借出超过该时长的对象被认为是泄漏，0表示不检测
+	// T 不是指针、chan 或者接口类型时不能设置
+	LeakThreshold time.Duration
+	// 发现泄漏时的回调，默认打印日志，同一次借出只回调一次
+	OnLeak func(Leak)
+	// 归还时检查对象是否借出过，重复归还返回 ErrConnReturned
+	// 设置了 LeakThreshold 时总是检查，T 不是指针、chan 或者接口类型时不能设置
+	CheckReturn bool
+}
+
+// Leak 借出时间过长的对象
+type Leak struct {
+	// 借出的对象
+	Object interface{}
+	// 借出时间
+	BorrowedAt time.Time
+	// 已经借出的时长
+	Held time.Duration
+	// 借出时的调用栈
+	Stack []byte
+}
+
+// borrowed 借出对象的记录
+type borrowed struct {
+	at       time.Time
+	stack    []byte
+	reported bool
+}
+
+// ObjectPool 通用对象池，用于缓冲区、解析器、客户端等创建成本高的对象
+type ObjectPool[T any] struct {
+	mu  sync.Mutex
+	cfg ObjectConfig[T]
+	// 空闲对象，后进先出
+	idle []T
+	// 借出的对象，只在设置了 LeakThreshold 或者 CheckReturn 时记录指针一类的对象
+	borrowed map[interface{}]*borrowed
+	// 对象池是否已经释放
+	closed bool
+	// 泄漏检测协程
+	leakCheck *periodic
+}
+
+// NewObjectPool 初始化对象池
+func NewObjectPool[T any](poolConfig *ObjectConfig[T]) (*ObjectPool[T], error) {
+	if poolConfig.MaxIdle < 0 || poolConfig.MaxSize < 0 || poolConfig.LeakThreshold < 0 {
+		return nil, errors.New("invalid capacity settings")
+	}
+	if poolConfig.MaxSize > 0 && poolConfig.Size == nil {
+		return nil, errors.New("invalid size func settings")
+	}
+	if poolConfig.New == nil {
+		return nil, errors.New("invalid new func setting")
+	}
+	tracking := poolConfig.LeakThreshold > 0 || poolConfig.CheckReturn
+	if tracking && !trackableType(reflect.TypeOf((*T)(nil)).Elem()) {
+		return nil, errors.New("leak detection requires a pointer, chan or interface object type")
+	}
+
+	p := &ObjectPool[T]{
+		cfg:  *poolConfig,
+		idle: make([]T, 0, poolConfig.MaxIdle),
+	}
+	if tracking {
+		p.borrowed = make(map[interface{}]*borrowed)
+	}
+	if p.cfg.OnLeak == nil {
+		p.cfg.OnLeak = func(l Leak) {
+			log.Printf("object %v borrowed for %v, borrowed at:\n%s", l.Object, l.Held, l.Stack)
+		}
+	}
+	if threshold := poolConfig.LeakThreshold; threshold > 0 {
+		p.leakCheck = startPeriodic(checkInterval(threshold), func() {
+			p.reportLeaks(threshold)
+		})
+	}
+	return p, nil
+}
+
+// Get 从对象池中取一个对象，没有空闲对象时新建
+func (p *ObjectPool[T]) Get() (T, error) {
+	var zero T
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return zero, ErrClosed
+		}
+		var obj T
+		fromIdle := false
+		if n := len(p.idle); n > 0 {
+			obj = p.idle[n-1]
+			p.idle[n-1] = zero
+			p.idle = p.idle[:n-1]
+			fromIdle = true
+		}
+		p.mu.Unlock()
+
+		if !fromIdle {
+			var err error
+			if obj, err = p.cfg.New(); err != nil {
+				return zero, err
+			}
+		}
+		if p.cfg.OnBorrow != nil {
+			if err := p.cfg.OnBorrow(obj); err != nil {
+				p.destroy(obj)
+				// 空闲对象失效换下一个，新建的对象失效直接返回错误
+				if fromIdle {
+					continue
+				}
+				return zero, err
+			}
+		}
+		p.track(obj)
+		return obj, nil
+	}
+}
+
+// Put 归还对象，对象池已满、对象过大或者重置失败时丢弃对象
+func (p *ObjectPool[T]) Put(obj T) error {
+	if !p.untrack(obj) {
+		return ErrConnReturned
+	}
+	if p.cfg.OnReturn != nil {
+		if err := p.cfg.OnReturn(obj); err != nil {
+			p.destroy(obj)
+			return err
+		}
+	}
+	if p.cfg.MaxSize > 0 && p.cfg.Size(obj) > p.cfg.MaxSize {
+		p.destroy(obj)
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.cfg.MaxIdle {
+		p.mu.Unlock()
+		p.destroy(obj)
+		return nil
+	}
+	p.idle = append(p.idle, obj)
+	p.mu.Unlock()
+	return nil
+}
+
+// Discard 丢弃一个借出的对象，不再放回对象池
+func (p *ObjectPool[T]) Discard(obj T) {
+	p.untrack(obj)
+	p.destroy(obj)
+}
+
+// Release 释放对象池，丢弃所有空闲对象，借出的对象在归还时丢弃
+func (p *ObjectPool[T]) Release() {
+	p.leakCheck.Stop()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, obj := range idle {
+		p.destroy(obj)
+	}
+}
+
+// Len 空闲对象数量
+func (p *ObjectPool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// Leaks 返回当前借出时间超过 LeakThreshold 的对象
+func (p *ObjectPool[T]) Leaks() []Leak {
+	threshold := p.cfg.LeakThreshold
+	if threshold <= 0 {
+		return nil
+	}
+	now := time.Now()
+	var leaks []Leak
+	p.mu.Lock()
+	for obj, b := range p.borrowed {
+		if held := now.Sub(b.at); held > threshold {
+			leaks = append(leaks, Leak{Object: obj, BorrowedAt: b.at, Held: held, Stack: b.stack})
+		}
+	}
+	p.mu.Unlock()
+	return leaks
+}
+
+// track 记录借出的对象，设置了 LeakThreshold 时同时记录调用栈
+func (p *ObjectPool[T]) track(obj T) {
+	key := interface{}(obj)
+	if p.borrowed == nil || !trackable(key) {
+		return
+	}
+	b := &borrowed{at: time.Now()}
+	if p.cfg.LeakThreshold > 0 {
+		b.stack = debug.Stack()
+	}
+	p.mu.Lock()
+	p.borrowed[key] = b
+	p.mu.Unlock()
+}
+
+// untrack 删除借出记录，对象没有被借出(重复归还)时返回false
+// 不记录的对象总是返回true
+func (p *ObjectPool[T]) untrack(obj T) bool {
+	key := interface{}(obj)
+	if p.borrowed == nil || !trackable(key) {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.borrowed[key]; !ok {
+		return false
+	}
+	delete(p.borrowed, key)
+	return true
+}
+
+// trackable 只记录指针一类的对象，值类型的对象相等并不代表是同一个对象
+func trackable(key interface{}) bool {
+	if key == nil {
+		return false
+	}
+	return trackableType(reflect.TypeOf(key))
+}
+
+// trackableType 类型 T 的对象能否被记录，接口类型要到运行时根据动态类型判断
+func trackableType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func (p *ObjectPool[T]) destroy(obj T) {
+	if p.cfg.Destroy != nil {
+		p.cfg.Destroy(obj)
+	}
+}
+
+// reportLeaks 对新发现的泄漏对象调用 OnLeak
+func (p *ObjectPool[T]) reportLeaks(threshold time.Duration) {
+	now := time.Now()
+	var leaks []Leak
+	p.mu.Lock()
+	for obj, b := range p.borrowed {
+		if held := now.Sub(b.at); held > threshold && !b.reported {
+			b.reported = true
+			leaks = append(leaks, Leak{Object: obj, BorrowedAt: b.at, Held: held, Stack: b.stack})
+		}
+	}
+	p.mu.Unlock()
+
+	for _, l := range leaks {
+		p.cfg.OnLeak(l)
+	}
+}
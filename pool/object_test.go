@@ -0,0 +1,192 @@
+package pool
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newBufferPool(t *testing.T, cfg ObjectConfig[*bytes.Buffer]) (*ObjectPool[*bytes.Buffer], *int) {
+	t.Helper()
+	destroyed := 0
+	cfg.New = func() (*bytes.Buffer, error) { return new(bytes.Buffer), nil }
+	cfg.Destroy = func(*bytes.Buffer) { destroyed++ }
+	p, err := NewObjectPool(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, &destroyed
+}
+
+func TestObjectPoolReuse(t *testing.T) {
+	p, destroyed := newBufferPool(t, ObjectConfig[*bytes.Buffer]{
+		MaxIdle:  1,
+		OnReturn: func(b *bytes.Buffer) error { b.Reset(); return nil },
+		Size:     func(b *bytes.Buffer) int { return b.Cap() },
+		MaxSize:  1024,
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	a.WriteString("hello")
+	_ = p.Put(a)
+	if b, _ := p.Get(); b != a || b.Len() != 0 {
+		t.Fatal("Get did not return the reset idle object")
+	}
+
+	// 超过 MaxSize 的对象不再复用
+	a.Grow(4096)
+	_ = p.Put(a)
+	if p.Len() != 0 || *destroyed != 1 {
+		t.Fatalf("idle=%d destroyed=%d after returning an oversized object", p.Len(), *destroyed)
+	}
+
+	// 空闲对象满了之后丢弃
+	x, _ := p.Get()
+	y, _ := p.Get()
+	_ = p.Put(x)
+	_ = p.Put(y)
+	if p.Len() != 1 || *destroyed != 2 {
+		t.Fatalf("idle=%d destroyed=%d, want 1 and 2", p.Len(), *destroyed)
+	}
+}
+
+func TestObjectPoolOnBorrow(t *testing.T) {
+	fail := false
+	p, destroyed := newBufferPool(t, ObjectConfig[*bytes.Buffer]{
+		MaxIdle: 1,
+		OnBorrow: func(*bytes.Buffer) error {
+			if fail {
+				return errors.New("broken")
+			}
+			return nil
+		},
+	})
+	defer p.Release()
+
+	a, _ := p.Get()
+	_ = p.Put(a)
+	// 失效的空闲对象被丢弃，新建的对象失效直接返回错误
+	fail = true
+	if _, err := p.Get(); err == nil {
+		t.Fatal("Get succeeded although OnBorrow failed")
+	}
+	if p.Len() != 0 || *destroyed != 2 {
+		t.Fatalf("idle=%d destroyed=%d, want 0 and 2", p.Len(), *destroyed)
+	}
+}
+
+func TestObjectPoolCheckReturn(t *testing.T) {
+	p, _ := newBufferPool(t, ObjectConfig[*bytes.Buffer]{MaxIdle: 2, CheckReturn: true})
+	defer p.Release()
+
+	a, _ := p.Get()
+	if err := p.Put(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(a); err != ErrConnReturned {
+		t.Fatalf("second Put = %v, want ErrConnReturned", err)
+	}
+	if err := p.Put(new(bytes.Buffer)); err != ErrConnReturned {
+		t.Fatalf("Put of a foreign object = %v, want ErrConnReturned", err)
+	}
+
+	b, _ := p.Get()
+	p.Discard(b)
+	if n := len(p.borrowed); n != 0 {
+		t.Fatalf("%d objects still tracked after Discard", n)
+	}
+}
+
+func TestObjectPoolNoTracking(t *testing.T) {
+	// 没有开启检测时不记录借出的对象，直接丢弃的对象可以被回收
+	p, _ := newBufferPool(t, ObjectConfig[*bytes.Buffer]{MaxIdle: 1})
+	defer p.Release()
+
+	for i := 0; i < 10; i++ {
+		_, _ = p.Get()
+	}
+	if p.borrowed != nil {
+		t.Fatalf("%d objects tracked without CheckReturn or LeakThreshold", len(p.borrowed))
+	}
+}
+
+func TestObjectPoolInvalidConfig(t *testing.T) {
+	newBytes := func() ([]byte, error) { return nil, nil }
+	for _, cfg := range []ObjectConfig[[]byte]{
+		{New: newBytes, LeakThreshold: time.Second},
+		{New: newBytes, CheckReturn: true},
+		{New: newBytes, MaxSize: 1},
+		{MaxIdle: 1},
+	} {
+		cfg := cfg
+		if _, err := NewObjectPool(&cfg); err == nil {
+			t.Errorf("NewObjectPool accepted %+v", cfg)
+		}
+	}
+}
+
+func TestObjectPoolLeaks(t *testing.T) {
+	var mu sync.Mutex
+	var leaks []Leak
+	p, _ := newBufferPool(t, ObjectConfig[*bytes.Buffer]{
+		MaxIdle:       1,
+		LeakThreshold: 5 * time.Millisecond,
+		OnLeak: func(l Leak) {
+			mu.Lock()
+			leaks = append(leaks, l)
+			mu.Unlock()
+		},
+	})
+	defer p.Release()
+
+	held, _ := p.Get()
+	returned, _ := p.Get()
+	_ = p.Put(returned)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(leaks)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("leak was not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// 同一次借出只回调一次
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(leaks) != 1 || leaks[0].Object != held || len(leaks[0].Stack) == 0 {
+		t.Fatalf("unexpected leaks %+v", leaks)
+	}
+	if l := p.Leaks(); len(l) != 1 || l[0].Object != held {
+		t.Fatalf("Leaks() = %+v", l)
+	}
+}
+
+func TestObjectPoolRelease(t *testing.T) {
+	p, destroyed := newBufferPool(t, ObjectConfig[*bytes.Buffer]{MaxIdle: 2})
+	a, _ := p.Get()
+	b, _ := p.Get()
+	_ = p.Put(a)
+
+	p.Release()
+	if *destroyed != 1 {
+		t.Fatalf("destroyed=%d after Release, want the idle object", *destroyed)
+	}
+	// 释放后归还的对象直接丢弃
+	_ = p.Put(b)
+	if *destroyed != 2 || p.Len() != 0 {
+		t.Fatalf("destroyed=%d idle=%d after Put on a released pool", *destroyed, p.Len())
+	}
+	if _, err := p.Get(); err != ErrClosed {
+		t.Fatalf("Get after Release = %v, want ErrClosed", err)
+	}
+}
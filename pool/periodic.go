@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// periodic 每隔固定时间执行一次任务的后台协程
+type periodic struct {
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// startPeriodic 启动后台协程，每隔 interval 调用一次 fn
+func startPeriodic(interval time.Duration, fn func()) *periodic {
+	p := &periodic{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+	return p
+}
+
+// Stop 通知后台协程退出并等待其结束，可以重复调用，nil 时什么都不做
+func (p *periodic) Stop() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// checkInterval 超时类检查的间隔，取超时时间的一半
+func checkInterval(timeout time.Duration) time.Duration {
+	if interval := timeout / 2; interval > 0 {
+		return interval
+	}
+	return timeout
+}
@@ -1,6 +1,9 @@
 package pool
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	ErrClosed = errors.New("pool is closed")
@@ -8,8 +11,15 @@ var (
 
 type Pool interface {
 	Get() (interface{}, error)
+	// GetContext 获取连接，等待时可以通过ctx取消或者设置超时
+	GetContext(ctx context.Context) (interface{}, error)
 	Put(interface{}) error
 	Close(interface{}) error
 	Release()
+	// Shutdown 不再借出连接并唤醒所有等待者，等待借出的连接全部归还后关闭所有连接
+	// ctx 结束时不再等待，返回 ctx.Err()
+	Shutdown(ctx context.Context) error
 	Len() int
+	// Stats 返回连接池当前状态的快照
+	Stats() Stats
 }
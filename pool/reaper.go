@@ -0,0 +1,79 @@
+package pool
+
+// reap 检查当前所有空闲连接，关闭空闲超时或者 Ping 失败的连接
+func (c *channelPool) reap() {
+	// 先把空闲连接全部取出，在锁外检查，检查期间新放回的连接留到下一轮
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	conns := c.conns
+	c.conns = make([]*idleConn, 0, c.maxIdle)
+	for _, wrapConn := range conns {
+		c.unmarkIdle(wrapConn.conn)
+	}
+	c.mu.Unlock()
+
+	var alive []*idleConn
+	for _, wrapConn := range conns {
+		if c.validate(wrapConn, c.testPolicy != TestNever) {
+			alive = append(alive, wrapConn)
+		}
+	}
+
+	c.mu.Lock()
+	// 检查期间到来的等待者优先
+	for len(alive) > 0 {
+		req := c.nextConnReq()
+		if req == nil {
+			break
+		}
+		req <- connReq{idleConn: alive[len(alive)-1]}
+		alive = alive[:len(alive)-1]
+	}
+	// 留下来的连接比检查期间放回的连接更早，放回队首
+	var overflow []*idleConn
+	if !c.closed {
+		if room := c.maxIdle - len(c.conns); len(alive) > room {
+			overflow = alive[:len(alive)-room]
+			alive = alive[len(alive)-room:]
+		}
+		c.conns = append(alive, c.conns...)
+		for _, wrapConn := range alive {
+			c.markIdle(wrapConn.conn)
+		}
+	} else {
+		overflow = alive
+	}
+	c.mu.Unlock()
+
+	for _, wrapConn := range overflow {
+		_ = c.Close(wrapConn.conn)
+	}
+}
+
+// fillMinIdle 新建连接直到空闲连接数达到 minIdle 或者连接数达到上限
+func (c *channelPool) fillMinIdle() {
+	for {
+		c.mu.Lock()
+		if c.closed || len(c.conns) >= c.minIdle || c.openingConns >= c.maxActive {
+			c.mu.Unlock()
+			return
+		}
+		// 先占用名额，避免创建连接期间 Get 超出最大连接数
+		c.openingConns++
+		c.mu.Unlock()
+
+		conn, err := c.factory()
+		c.mu.Lock()
+		if err != nil {
+			c.freeSlot()
+			c.mu.Unlock()
+			return
+		}
+		wrapConn := c.newIdleConn(conn)
+		c.mu.Unlock()
+		_ = c.putIdle(wrapConn)
+	}
+}
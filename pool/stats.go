@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/limitzhang87/go-utils/atomic"
+)
+
+// Stats 连接池状态快照
+type Stats struct {
+	// 已打开的连接数量(空闲 + 使用中)
+	OpenConns int
+	// 空闲连接数量
+	IdleConns int
+	// 正在被使用的连接数量
+	InUseConns int
+	// 当前正在等待连接的数量
+	Waiters int
+
+	// 累计等待连接的次数
+	WaitCount int64
+	// 累计等待连接的时长
+	WaitDuration time.Duration
+	// 累计等待超时(或者被取消)的次数
+	Timeouts int64
+	// 累计因为空闲超时被关闭的连接数
+	IdleClosed int64
+	// 累计 Ping 失败被关闭的连接数
+	PingFailed int64
+	// 累计因为超过最长存活时间被关闭的连接数
+	LifetimeClosed int64
+}
+
+// StatsHook 连接池事件回调，可以用来把状态导出到监控系统
+// 回调在连接池的锁之外执行，但会阻塞当前调用，不要在里面做耗时操作
+type StatsHook interface {
+	// OnWait 一次等待结束，d 为等待时长
+	OnWait(d time.Duration)
+	// OnTimeout 等待超时或者被取消
+	OnTimeout()
+	// OnIdleClosed 空闲超时的连接被关闭
+	OnIdleClosed()
+	// OnPingFailed Ping 失败的连接被关闭
+	OnPingFailed()
+	// OnLifetimeClosed 超过最长存活时间的连接被关闭
+	OnLifetimeClosed()
+}
+
+// poolStats 累计计数器，使用原子操作更新
+type poolStats struct {
+	waitCount    atomic.Int64
+	waitDuration atomic.Duration
+	timeouts     atomic.Int64
+	idleClosed   atomic.Int64
+	pingFailed   atomic.Int64
+	lifetime     atomic.Int64
+	hook         StatsHook
+}
+
+func (s *poolStats) recordWait(d time.Duration) {
+	s.waitCount.Add(1)
+	s.waitDuration.Add(d)
+	if s.hook != nil {
+		s.hook.OnWait(d)
+	}
+}
+
+func (s *poolStats) recordTimeout() {
+	s.timeouts.Add(1)
+	if s.hook != nil {
+		s.hook.OnTimeout()
+	}
+}
+
+func (s *poolStats) recordIdleClosed() {
+	s.idleClosed.Add(1)
+	if s.hook != nil {
+		s.hook.OnIdleClosed()
+	}
+}
+
+func (s *poolStats) recordPingFailed() {
+	s.pingFailed.Add(1)
+	if s.hook != nil {
+		s.hook.OnPingFailed()
+	}
+}
+
+func (s *poolStats) recordLifetimeClosed() {
+	s.lifetime.Add(1)
+	if s.hook != nil {
+		s.hook.OnLifetimeClosed()
+	}
+}
+
+// fill 将累计计数写入快照
+func (s *poolStats) fill(st *Stats) {
+	st.WaitCount = s.waitCount.Load()
+	st.WaitDuration = s.waitDuration.Load()
+	st.Timeouts = s.timeouts.Load()
+	st.IdleClosed = s.idleClosed.Load()
+	st.PingFailed = s.pingFailed.Load()
+	st.LifetimeClosed = s.lifetime.Load()
+}
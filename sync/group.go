@@ -0,0 +1,150 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Group 一组并发执行的任务，类似 errgroup
+// 第一个任务返回错误时取消共享的 ctx，Wait 返回所有任务的错误
+// 零值可以直接使用，这时任务拿到的 ctx 是 context.Background()，出错时不会取消
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     Wait
+	// 限制同时执行的任务数量，为nil时不限制
+	sem Semaphore
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// MultiError 多个任务返回的错误
+type MultiError struct {
+	Errors []error
+}
+
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap 返回所有的错误，Go 1.20 起 errors.Is / errors.As 会用它检查每一个错误
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// Is 其中任意一个错误匹配 target 时返回true，在 Go 1.20 之前也能用于 errors.Is
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 把第一个匹配 target 的错误赋值给 target，在 Go 1.20 之前也能用于 errors.As
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGroup 创建任务组，任务拿到的 ctx 派生自 ctx
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// SetLimit 限制同时执行的任务数量，n < 0 表示不限制
+// 必须在调用 Go 之前设置
+func (g *Group) SetLimit(n int) {
+	if g.wg.Count() != 0 {
+		panic("sync: modify limit while goroutines in the group are still active")
+	}
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = NewSemaphore(n)
+}
+
+// Go 在新的 goroutine 中执行 f，达到并发限制时阻塞到有任务结束
+func (g *Group) Go(f func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem.Lock()
+	}
+	g.start(f)
+}
+
+// TryGo 达到并发限制时不执行 f，返回false
+func (g *Group) TryGo(f func(ctx context.Context) error) bool {
+	if g.sem != nil && !g.sem.TryLock() {
+		return false
+	}
+	g.start(f)
+	return true
+}
+
+func (g *Group) start(f func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			if g.sem != nil {
+				g.sem.Unlock()
+			}
+			g.wg.Done()
+		}()
+		if err := f(g.taskCtx()); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			// 第一个错误出现时通知其他任务退出
+			g.doCancel()
+		}
+	}()
+}
+
+// Wait 等待所有任务结束，没有错误返回nil，只有一个错误时返回该错误，
+// 多个错误时返回 *MultiError
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.doCancel()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch len(g.errs) {
+	case 0:
+		return nil
+	case 1:
+		return g.errs[0]
+	default:
+		errs := make([]error, len(g.errs))
+		copy(errs, g.errs)
+		return &MultiError{Errors: errs}
+	}
+}
+
+// taskCtx 任务使用的 ctx，零值的 Group 使用 context.Background()
+func (g *Group) taskCtx() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// doCancel 取消任务的 ctx，零值的 Group 没有可以取消的 ctx
+func (g *Group) doCancel() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+}
@@ -1,5 +1,10 @@
 package sync
 
+import (
+	"context"
+	"time"
+)
+
 // channel 实现sync.Once
 type Once chan struct{}
 
@@ -18,12 +23,13 @@ func (o Once) Do(f func()) {
 		return
 	}
 
+	// 关闭通道，这里江辉使用所以所有的等待
+	// 以及未来会调用Do方法的goroutine
+	// 用 defer 保证 f panic 时也会关闭，否则其他 goroutine 会永远阻塞
+	defer close(o)
 	// 调用f, 因为channel中只有一个值
 	// 所以只有一个goroutine会到达这里
 	f()
-	// 关闭通道，这里江辉使用所以所有的等待
-	// 以及未来会调用Do方法的goroutine
-	close(o)
 }
 
 // channel 实现信号量
@@ -43,6 +49,38 @@ func (s Semaphore) Unlock() {
 	<-s
 }
 
+// TryLock 尝试获取信号量，不阻塞，返回是否获取成功
+func (s Semaphore) TryLock() bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// LockContext 获取信号量，ctx 结束时放弃并返回 ctx.Err()
+func (s Semaphore) LockContext(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LockTimeout 获取信号量，最多等待 d，返回是否获取成功
+func (s Semaphore) LockTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // 实现互斥锁
 type Mutex Semaphore
 
@@ -50,57 +88,207 @@ func NewMutex() Mutex {
 	return Mutex(NewSemaphore(1))
 }
 
-// 读写锁
+func (m Mutex) Lock() {
+	Semaphore(m).Lock()
+}
+
+func (m Mutex) Unlock() {
+	Semaphore(m).Unlock()
+}
+
+// TryLock 尝试加锁，不阻塞，返回是否加锁成功
+func (m Mutex) TryLock() bool {
+	return Semaphore(m).TryLock()
+}
+
+// LockContext 加锁，ctx 结束时放弃并返回 ctx.Err()
+func (m Mutex) LockContext(ctx context.Context) error {
+	return Semaphore(m).LockContext(ctx)
+}
+
+// LockTimeout 加锁，最多等待 d，返回是否加锁成功
+func (m Mutex) LockTimeout(d time.Duration) bool {
+	return Semaphore(m).LockTimeout(d)
+}
+
+// 读写锁，写优先：有写锁在等待时新的读锁也要等待，避免源源不断的读锁把写锁饿死
 type RWMutex struct {
-	write   chan struct{}
-	readers chan int
+	// 保护读写锁的状态，跟 WaitGroup 一样把通道当作状态的互斥量
+	state chan rwState
 }
 
-func NewLock() RWMutex {
-	return RWMutex{
-		// 用来实现一个普通的互斥锁
-		write: make(chan struct{}, 1),
-		// 用来保护读锁的数量，获取读锁时通过接受通道里的值确保
-		// 其他goroutine不会在同一个时间更改读锁的数量
-		readers: make(chan int, 1),
+type rwState struct {
+	// 持有读锁的数量
+	readers int
+	// 是否有人持有写锁
+	writer bool
+	// 正在等待写锁的数量
+	writersWaiting int
+	// 等待读锁的 goroutine 阻塞在这个通道上，状态变化时close掉唤醒所有等待者
+	readWake chan struct{}
+	// 等待写锁的 goroutine 阻塞在这个通道上
+	writeWake chan struct{}
+}
+
+// readWait 返回读锁等待通道，没有时新建
+func (st *rwState) readWait() chan struct{} {
+	if st.readWake == nil {
+		st.readWake = make(chan struct{})
+	}
+	return st.readWake
+}
+
+// writeWait 返回写锁等待通道，没有时新建
+func (st *rwState) writeWait() chan struct{} {
+	if st.writeWake == nil {
+		st.writeWake = make(chan struct{})
+	}
+	return st.writeWake
+}
+
+// wakeReaders 唤醒所有等待读锁的 goroutine, 醒来之后它们会重新检查状态
+func (st *rwState) wakeReaders() {
+	if st.readWake != nil {
+		close(st.readWake)
+		st.readWake = nil
+	}
+}
+
+// wakeWriters 唤醒所有等待写锁的 goroutine
+func (st *rwState) wakeWriters() {
+	if st.writeWake != nil {
+		close(st.writeWake)
+		st.writeWake = nil
 	}
 }
 
+func NewLock() RWMutex {
+	l := RWMutex{state: make(chan rwState, 1)}
+	l.state <- rwState{}
+	return l
+}
+
 func (l RWMutex) Lock() {
-	l.write <- struct{}{}
+	_ = l.LockContext(context.Background())
 }
 
 func (l RWMutex) Unlock() {
-	<-l.write
+	st := <-l.state
+	if !st.writer {
+		l.state <- st
+		panic("sync: unlock of unlocked RWMutex")
+	}
+	st.writer = false
+	// 写锁优先，但是读锁也一起唤醒，有写锁在等待时它们会继续等待
+	st.wakeWriters()
+	st.wakeReaders()
+	l.state <- st
+}
+
+// TryLock 尝试加写锁，不阻塞，返回是否加锁成功
+func (l RWMutex) TryLock() bool {
+	st := <-l.state
+	defer func() { l.state <- st }()
+	if st.writer || st.readers > 0 {
+		return false
+	}
+	st.writer = true
+	return true
+}
+
+// LockContext 加写锁，ctx 结束时放弃并返回 ctx.Err()
+func (l RWMutex) LockContext(ctx context.Context) error {
+	st := <-l.state
+	// 登记为等待中的写锁，之后新来的读锁都会等待
+	st.writersWaiting++
+	for {
+		if !st.writer && st.readers == 0 {
+			st.writersWaiting--
+			st.writer = true
+			l.state <- st
+			return nil
+		}
+		wait := st.writeWait()
+		l.state <- st
+
+		select {
+		case <-wait:
+			st = <-l.state
+		case <-ctx.Done():
+			st = <-l.state
+			st.writersWaiting--
+			if st.writersWaiting == 0 && !st.writer {
+				// 最后一个等待的写锁离开了，被挡住的读锁可以继续了
+				st.wakeReaders()
+			}
+			l.state <- st
+			return ctx.Err()
+		}
+	}
+}
+
+// LockTimeout 加写锁，最多等待 d，返回是否加锁成功
+func (l RWMutex) LockTimeout(d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return l.LockContext(ctx) == nil
 }
 
 func (l RWMutex) RLock() {
-	// 统计当前锁的数量， 默认为0
-	var rs int
-	select {
-	case l.write <- struct{}{}:
-		// 如果write通道能发送成功，证明这是第一个加读锁的
-		// 向write通道发送一个值，防止出现并发的读-写
-	case rs = <-l.readers:
-		// 能从通道里接到的值，证明RWMutex上已经有读锁了，下面会更新读锁
+	_ = l.RLockContext(context.Background())
+}
+
+// TryRLock 尝试加读锁，不阻塞，返回是否加锁成功
+func (l RWMutex) TryRLock() bool {
+	st := <-l.state
+	defer func() { l.state <- st }()
+	if st.writer || st.writersWaiting > 0 {
+		return false
+	}
+	st.readers++
+	return true
+}
+
+// RLockContext 加读锁，ctx 结束时放弃并返回 ctx.Err()
+func (l RWMutex) RLockContext(ctx context.Context) error {
+	for {
+		st := <-l.state
+		// 没有写锁，也没有写锁在等待
+		if !st.writer && st.writersWaiting == 0 {
+			st.readers++
+			l.state <- st
+			return nil
+		}
+		wait := st.readWait()
+		l.state <- st
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	// 如果执行了 l.write <- struct{}{}, rs的值会是0
-	rs++
-	// 更新RWMutex读锁数量
-	l.readers <- rs
+}
+
+// RLockTimeout 加读锁，最多等待 d，返回是否加锁成功
+func (l RWMutex) RLockTimeout(d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return l.RLockContext(ctx) == nil
 }
 
 func (l RWMutex) RUnlock() {
-	// 读出读锁数量然后减一
-	rs := <-l.readers
-	rs--
-	// 如果释放读锁后读锁的数量变为0了，抽空write通道，让write通道变为可用
-	if rs == 0 {
-		<-l.write
-		return
+	st := <-l.state
+	if st.readers <= 0 {
+		l.state <- st
+		panic("sync: RUnlock of unlocked RWMutex")
 	}
-	// 如果释放读锁的数量减一后不是0，把新的读锁数量发送给readers通道
-	l.readers <- rs
+	st.readers--
+	// 最后一个读锁释放，唤醒等待的写锁
+	if st.readers == 0 {
+		st.wakeWriters()
+	}
+	l.state <- st
 }
 
 // waitGroup
@@ -141,7 +329,8 @@ func NewWaitGroup() WaitGroup {
 
 func (wg WaitGroup) Add(delta int) {
 	// 当前当前的世代
-	g := <-wg
+	old := <-wg
+	g := old
 	if g.n == 0 {
 		// 计数器是0，创建一个新的世代
 		g = newGeneration()
@@ -149,6 +338,8 @@ func (wg WaitGroup) Add(delta int) {
 	g.n += delta
 	if g.n < 0 {
 		// 跟sync库里的WaitGroup一样，不允许计数器为负数
+		// 先把原来的世代放回去，recover 之后 WaitGroup 还能继续使用
+		wg <- old
 		panic("negative WaitGroup count")
 	}
 	if g.n == 0 {
@@ -157,12 +348,22 @@ func (wg WaitGroup) Add(delta int) {
 		g.end()
 	}
 	// 将更新后的世代发送回WaitGroup通道
+	wg <- g
 }
 
 func (wg WaitGroup) Done() {
 	wg.Add(-1)
 }
 
+// Go 计数器加一并在新的 goroutine 中执行 f，执行结束后计数器减一
+func (wg WaitGroup) Go(f func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
 func (wg WaitGroup) Wait() {
 	// 获取当前世代
 	g := <-wg
@@ -176,3 +377,23 @@ func (wg WaitGroup) Wait() {
 	// 直到WaitGroup的计数器回到0，wait通道被close才会接触阻塞
 	<-wait
 }
+
+// WaitContext 和 Wait 一样，ctx 结束时返回 ctx.Err()，不会留下阻塞的 goroutine
+func (wg WaitGroup) WaitContext(ctx context.Context) error {
+	g := <-wg
+	wait := g.wait
+	wg <- g
+	select {
+	case <-wait:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Count 返回当前世代还没有完成的任务数量
+func (wg WaitGroup) Count() int {
+	g := <-wg
+	wg <- g
+	return g.n
+}
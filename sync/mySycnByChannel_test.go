@@ -0,0 +1,224 @@
+package sync
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRWMutexWriterNotStarved(t *testing.T) {
+	l := NewLock()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	// 源源不断的读锁，任何时刻都至少有一个读锁被持有
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				l.RLock()
+				time.Sleep(100 * time.Microsecond)
+				l.RUnlock()
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	if !l.LockTimeout(time.Second) {
+		t.Fatal("writer starved by a stream of readers")
+	}
+	l.Unlock()
+}
+
+func TestRWMutexPendingWriterBlocksReaders(t *testing.T) {
+	l := NewLock()
+	l.RLock()
+
+	locked := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(locked)
+	}()
+	// 等待写锁登记为等待中
+	deadline := time.Now().Add(time.Second)
+	for l.TryRLock() {
+		l.RUnlock()
+		if time.Now().After(deadline) {
+			t.Fatal("pending writer never blocked new readers")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	if l.RLockTimeout(5 * time.Millisecond) {
+		t.Fatal("new reader got the lock while a writer was waiting")
+	}
+
+	l.RUnlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("writer did not get the lock after the last reader left")
+	}
+	l.Unlock()
+}
+
+func TestRWMutexTryLock(t *testing.T) {
+	l := NewLock()
+	if !l.TryLock() {
+		t.Fatal("TryLock on unlocked mutex failed")
+	}
+	if l.TryLock() || l.TryRLock() {
+		t.Fatal("TryLock/TryRLock succeeded while write locked")
+	}
+	l.Unlock()
+
+	if !l.TryRLock() || !l.TryRLock() {
+		t.Fatal("TryRLock failed on read locked mutex")
+	}
+	if l.TryLock() {
+		t.Fatal("TryLock succeeded while read locked")
+	}
+	l.RUnlock()
+	l.RUnlock()
+	if !l.TryLock() {
+		t.Fatal("TryLock failed after all readers left")
+	}
+	l.Unlock()
+}
+
+func TestRWMutexLockContextCancel(t *testing.T) {
+	l := NewLock()
+	l.RLock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- l.LockContext(ctx)
+	}()
+	time.Sleep(2 * time.Millisecond)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("LockContext = %v, want Canceled", err)
+	}
+
+	// 放弃的写锁不能继续挡住读锁
+	if !l.TryRLock() {
+		t.Fatal("readers still blocked after the pending writer gave up")
+	}
+	l.RUnlock()
+	l.RUnlock()
+
+	l.Lock()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	if err := l.RLockContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("RLockContext = %v, want DeadlineExceeded", err)
+	}
+	l.Unlock()
+	if !l.TryLock() {
+		t.Fatal("TryLock failed after cancelled waiters")
+	}
+	l.Unlock()
+}
+
+func TestMutexLockContextCancel(t *testing.T) {
+	m := NewMutex()
+	m.Lock()
+	if m.TryLock() {
+		t.Fatal("TryLock succeeded on locked mutex")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	if err := m.LockContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("LockContext = %v, want DeadlineExceeded", err)
+	}
+	m.Unlock()
+	if !m.LockTimeout(time.Second) {
+		t.Fatal("LockTimeout failed on unlocked mutex")
+	}
+	m.Unlock()
+}
+
+func TestWaitGroupRepeatedAdd(t *testing.T) {
+	wg := NewWaitGroup()
+	// 新建的 WaitGroup 不阻塞
+	wg.Wait()
+
+	var n int64
+	for round := 0; round < 5; round++ {
+		wg.Add(2)
+		wg.Add(3)
+		for i := 0; i < 5; i++ {
+			go func() {
+				atomic.AddInt64(&n, 1)
+				wg.Done()
+			}()
+		}
+		for i := 0; i < 10; i++ {
+			wg.Go(func() {
+				atomic.AddInt64(&n, 1)
+			})
+		}
+		wg.Wait()
+		if got := atomic.LoadInt64(&n); got != int64(15*(round+1)) {
+			t.Fatalf("round %d: %d tasks finished before Wait returned, want %d", round, got, 15*(round+1))
+		}
+		if c := wg.Count(); c != 0 {
+			t.Fatalf("round %d: Count = %d after Wait", round, c)
+		}
+	}
+}
+
+func TestWaitGroupWaitContext(t *testing.T) {
+	wg := NewWaitGroup()
+	release := make(chan struct{})
+	wg.Go(func() {
+		<-release
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	if err := wg.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitContext = %v, want DeadlineExceeded", err)
+	}
+	close(release)
+	if err := wg.WaitContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitGroupNegativeCount(t *testing.T) {
+	wg := NewWaitGroup()
+	wg.Add(1)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Add did not panic on a negative count")
+			}
+		}()
+		wg.Add(-2)
+	}()
+
+	// panic 之后状态没有变化，也不会因为通道为空而阻塞
+	if c := wg.Count(); c != 1 {
+		t.Fatalf("Count = %d after the panic, want 1", c)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	if err := wg.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitContext = %v, want DeadlineExceeded", err)
+	}
+	wg.Done()
+	if err := wg.WaitContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
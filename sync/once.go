@@ -0,0 +1,60 @@
+package sync
+
+import "sync"
+
+// OnceFunc 返回一个只会执行一次 f 的函数
+// f panic 时之后的每一次调用都会以同样的值 panic
+func OnceFunc(f func()) func() {
+	var (
+		once     sync.Once
+		panicked bool
+		p        interface{}
+	)
+	return func() {
+		once.Do(func() {
+			defer func() {
+				if p = recover(); p != nil {
+					panicked = true
+				}
+			}()
+			f()
+		})
+		if panicked {
+			panic(p)
+		}
+	}
+}
+
+// OnceValue 返回一个只在成功时缓存结果的函数
+// f 返回错误时不缓存，下一次调用会重新执行 f；并发调用时同一时间只有一个在执行 f
+func OnceValue[T any](f func() (T, error)) func() (T, error) {
+	var (
+		mu    sync.Mutex
+		done  bool
+		value T
+	)
+	return func() (T, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return value, nil
+		}
+		v, err := f()
+		if err != nil {
+			return v, err
+		}
+		value, done = v, true
+		return value, nil
+	}
+}
+
+// OnceErr 返回一个执行 f 直到成功为止的函数，成功之后的调用直接返回nil
+func OnceErr(f func() error) func() error {
+	g := OnceValue(func() (struct{}, error) {
+		return struct{}{}, f()
+	})
+	return func() error {
+		_, err := g()
+		return err
+	}
+}
@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOnceFunc(t *testing.T) {
+	var n int32
+	f := OnceFunc(func() { atomic.AddInt32(&n, 1) })
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	wg.Wait()
+	if n != 1 {
+		t.Fatalf("f ran %d times, want 1", n)
+	}
+}
+
+func TestOnceFuncPanic(t *testing.T) {
+	var n int
+	f := OnceFunc(func() {
+		n++
+		panic("boom")
+	})
+	// 第一次之后的调用不再执行 f，但是继续以同样的值 panic
+	for i := 0; i < 3; i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Fatalf("call %d recovered %v, want boom", i, r)
+				}
+			}()
+			f()
+		}()
+	}
+	if n != 1 {
+		t.Fatalf("f ran %d times, want 1", n)
+	}
+}
+
+func TestOnceValueRetry(t *testing.T) {
+	var n int
+	errFailed := errors.New("failed")
+	f := OnceValue(func() (int, error) {
+		n++
+		if n < 3 {
+			return 0, errFailed
+		}
+		return n, nil
+	})
+	// 失败的结果不缓存，直到成功为止
+	for i := 1; i < 3; i++ {
+		if _, err := f(); err != errFailed {
+			t.Fatalf("call %d: err = %v, want errFailed", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if v, err := f(); v != 3 || err != nil {
+			t.Fatalf("f() = %d, %v, want 3, nil", v, err)
+		}
+	}
+	if n != 3 {
+		t.Fatalf("f ran %d times, want 3", n)
+	}
+}
+
+func TestOnceValueConcurrent(t *testing.T) {
+	var n int32
+	f := OnceValue(func() (int32, error) {
+		return atomic.AddInt32(&n, 1), nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := f(); v != 1 || err != nil {
+				t.Errorf("f() = %d, %v, want 1, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOnceErr(t *testing.T) {
+	var n int
+	errFailed := errors.New("failed")
+	f := OnceErr(func() error {
+		n++
+		if n == 1 {
+			return errFailed
+		}
+		return nil
+	})
+	if err := f(); err != errFailed {
+		t.Fatalf("first call = %v, want errFailed", err)
+	}
+	if err := f(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f(); err != nil || n != 2 {
+		t.Fatalf("f() = %v after success, ran %d times, want nil and 2", err, n)
+	}
+}
@@ -0,0 +1,323 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrExceedsBurst = errors.New("sync: requested tokens exceed limiter burst")
+	ErrZeroBurst    = errors.New("sync: rate limited io requires a limiter burst greater than 0")
+)
+
+// Limit 每秒产生的令牌数
+type Limit float64
+
+// Inf 不限速
+const Inf = Limit(math.MaxFloat64)
+
+// Every 把事件间隔转换为 Limit
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// Limiter 令牌桶限速器
+// 令牌以 limit 的速度放入容量为 burst 的桶中，每个事件消耗一个令牌
+type Limiter struct {
+	mu    sync.Mutex
+	limit Limit
+	burst int
+	// 桶里剩余的令牌，预约之后可能为负数
+	tokens float64
+	// 上一次更新 tokens 的时间
+	last time.Time
+}
+
+// NewLimiter 创建限速器，初始时桶是满的
+func NewLimiter(r Limit, b int) *Limiter {
+	return &Limiter{limit: r, burst: b, tokens: float64(b)}
+}
+
+// Limit 当前每秒产生的令牌数
+func (l *Limiter) Limit() Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Burst 桶的容量
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// SetLimit 修改每秒产生的令牌数
+func (l *Limiter) SetLimit(r Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(time.Now())
+	l.limit = r
+}
+
+// SetBurst 修改桶的容量
+func (l *Limiter) SetBurst(b int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(time.Now())
+	l.burst = b
+	if l.tokens > float64(b) {
+		l.tokens = float64(b)
+	}
+}
+
+// Allow 是否可以立即执行一个事件
+func (l *Limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN 是否可以立即执行 n 个事件，可以时消耗 n 个令牌
+func (l *Limiter) AllowN(n int) bool {
+	return l.reserveN(time.Now(), n, 0).ok
+}
+
+// Reserve 预约一个令牌，调用方需要等待 Reservation.Delay 之后再执行
+func (l *Limiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN 预约 n 个令牌，n 超过 burst 时返回的 Reservation.OK 为false
+func (l *Limiter) ReserveN(n int) *Reservation {
+	return l.reserveN(time.Now(), n, time.Duration(math.MaxInt64))
+}
+
+// Wait 等待到可以执行一个事件，ctx 结束时返回 ctx.Err()
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN 等待到可以执行 n 个事件
+// n 超过 burst 或者等待时间会超过 ctx 的截止时间时立即返回错误
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n > l.Burst() && l.Limit() != Inf {
+		return ErrExceedsBurst
+	}
+
+	now := time.Now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	r := l.reserveN(now, n, maxWait)
+	if !r.ok {
+		return context.DeadlineExceeded
+	}
+
+	delay := r.delayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 没有用上的令牌还给桶
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// advance 按照经过的时间往桶里放令牌，调用方需要持有锁
+func (l *Limiter) advance(now time.Time) {
+	if l.last.IsZero() {
+		l.last = now
+		return
+	}
+	if now.Before(l.last) {
+		return
+	}
+	elapsed := now.Sub(l.last).Seconds()
+	l.tokens += elapsed * float64(l.limit)
+	if burst := float64(l.burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+}
+
+// reserveN 预约 n 个令牌，需要等待的时间超过 maxWait 时不预约
+func (l *Limiter) reserveN(now time.Time, n int, maxWait time.Duration) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit == Inf {
+		return &Reservation{ok: true, lim: l, timeToAct: now}
+	}
+	if n > l.burst {
+		return &Reservation{lim: l}
+	}
+
+	l.advance(now)
+	tokens := l.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		if l.limit <= 0 {
+			return &Reservation{lim: l}
+		}
+		wait = time.Duration(-tokens / float64(l.limit) * float64(time.Second))
+	}
+	if wait > maxWait {
+		return &Reservation{lim: l}
+	}
+	l.tokens = tokens
+	return &Reservation{ok: true, lim: l, tokens: n, timeToAct: now.Add(wait)}
+}
+
+// Reservation 预约的令牌
+type Reservation struct {
+	ok        bool
+	lim       *Limiter
+	tokens    int
+	timeToAct time.Time
+}
+
+// OK 是否预约成功，n 超过 burst 时预约失败
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 还需要等待多久才能执行
+func (r *Reservation) Delay() time.Duration {
+	return r.delayFrom(time.Now())
+}
+
+func (r *Reservation) delayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	if d := r.timeToAct.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel 放弃预约，还没到执行时间时把令牌还给桶
+func (r *Reservation) Cancel() {
+	if !r.ok || r.tokens == 0 {
+		return
+	}
+	l := r.lim
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if !r.timeToAct.After(now) {
+		return
+	}
+	l.advance(now)
+	l.tokens += float64(r.tokens)
+	if burst := float64(l.burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	r.tokens = 0
+}
+
+// RateReader 按照 Limiter 限制读取速度的 io.Reader，一个令牌对应一个字节
+type RateReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *Limiter
+}
+
+// NewRateReader 创建限速的 Reader，每次读取最多 burst 个字节，burst 必须大于0
+func NewRateReader(ctx context.Context, r io.Reader, l *Limiter) (*RateReader, error) {
+	if _, err := ioChunk(l); err != nil {
+		return nil, err
+	}
+	return &RateReader{ctx: ctx, r: r, l: l}, nil
+}
+
+// Read 读取之后等待对应数量的令牌，burst 被 SetBurst 改为0之后返回 ErrZeroBurst
+func (rr *RateReader) Read(p []byte) (int, error) {
+	burst, err := ioChunk(rr.l)
+	if err != nil {
+		return 0, err
+	}
+	if burst > 0 && len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := rr.r.Read(p)
+	if n > 0 {
+		if werr := rr.l.WaitN(rr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// RateWriter 按照 Limiter 限制写入速度的 io.Writer，一个令牌对应一个字节
+type RateWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *Limiter
+}
+
+// NewRateWriter 创建限速的 Writer，大的写入会按 burst 拆分，burst 必须大于0
+func NewRateWriter(ctx context.Context, w io.Writer, l *Limiter) (*RateWriter, error) {
+	if _, err := ioChunk(l); err != nil {
+		return nil, err
+	}
+	return &RateWriter{ctx: ctx, w: w, l: l}, nil
+}
+
+// Write 按 burst 拆分写入，每块写入前等待令牌，burst 被 SetBurst 改为0之后返回 ErrZeroBurst
+func (rw *RateWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		burst, err := ioChunk(rw.l)
+		if err != nil {
+			return written, err
+		}
+		chunk := p
+		if burst > 0 && len(chunk) > burst {
+			chunk = chunk[:burst]
+		}
+		if err := rw.l.WaitN(rw.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := rw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			// 底层 Writer 没有写入也没有报错，避免死循环
+			return written, io.ErrShortWrite
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// ioChunk 限速读写每次最多处理的字节数，0表示不限速不需要拆分
+func ioChunk(l *Limiter) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == Inf {
+		return 0, nil
+	}
+	if l.burst <= 0 {
+		return 0, ErrZeroBurst
+	}
+	return l.burst, nil
+}
@@ -0,0 +1,207 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	if l := Every(100 * time.Millisecond); math.Abs(float64(l)-10) > 1e-9 {
+		t.Fatalf("Every(100ms) = %v, want 10", l)
+	}
+	if l := Every(0); l != Inf {
+		t.Fatalf("Every(0) = %v, want Inf", l)
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l := NewLimiter(1, 3)
+	if !l.AllowN(2) || !l.Allow() {
+		t.Fatal("a full bucket did not allow burst events")
+	}
+	if l.Allow() {
+		t.Fatal("Allow succeeded on an empty bucket")
+	}
+	if r := l.ReserveN(4); r.OK() {
+		t.Fatal("ReserveN above burst succeeded")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	// 使用固定的时间计算，不依赖实际的等待
+	l := NewLimiter(10, 2)
+	t0 := time.Now()
+	if !l.reserveN(t0, 2, 0).ok {
+		t.Fatal("full bucket refused 2 tokens")
+	}
+	if l.reserveN(t0.Add(50*time.Millisecond), 1, 0).ok {
+		t.Fatal("token available before the refill interval")
+	}
+	if !l.reserveN(t0.Add(100*time.Millisecond), 1, 0).ok {
+		t.Fatal("token not refilled after 100ms at 10/s")
+	}
+
+	// 桶里最多放 burst 个令牌
+	t1 := t0.Add(time.Hour)
+	if !l.reserveN(t1, 2, 0).ok || l.reserveN(t1, 1, 0).ok {
+		t.Fatal("bucket refilled beyond burst")
+	}
+
+	// 令牌不够时预约，等待时间按速度计算
+	r := l.reserveN(t1, 1, time.Duration(math.MaxInt64))
+	if !r.ok {
+		t.Fatal("reservation failed")
+	}
+	if d := r.delayFrom(t1); d != 100*time.Millisecond {
+		t.Fatalf("delay = %v, want 100ms", d)
+	}
+	if d := r.delayFrom(t1.Add(time.Second)); d != 0 {
+		t.Fatalf("delay after timeToAct = %v, want 0", d)
+	}
+}
+
+func TestReservationCancel(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow()
+	r := l.Reserve()
+	if d := r.Delay(); d <= 0 || d > time.Second {
+		t.Fatalf("Delay = %v, want about 1s", d)
+	}
+	// 取消后令牌还给桶，下一次预约不用排在它后面
+	r.Cancel()
+	if d := l.Reserve().Delay(); d > time.Second {
+		t.Fatalf("Delay after Cancel = %v, want at most 1s", d)
+	}
+	// 重复取消不会多还令牌
+	r.Cancel()
+	if l.Allow() {
+		t.Fatal("repeated Cancel refunded tokens twice")
+	}
+}
+
+func TestLimiterWaitDeadline(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow()
+
+	// 截止时间之前等不到令牌时立即返回，不占用令牌
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Millisecond {
+		t.Fatalf("Wait blocked %v although the deadline could not be met", d)
+	}
+	if l.tokens < -0.5 {
+		t.Fatalf("tokens = %v, Wait reserved tokens it did not use", l.tokens)
+	}
+
+	if err := l.WaitN(context.Background(), 2); err != ErrExceedsBurst {
+		t.Fatalf("WaitN above burst = %v, want ErrExceedsBurst", err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(canceled); err != context.Canceled {
+		t.Fatalf("Wait with canceled ctx = %v, want Canceled", err)
+	}
+}
+
+func TestLimiterWaitCancelRefund(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("Wait = %v, want Canceled", err)
+	}
+	// 等待中被取消的预约把令牌还回去
+	if d := l.Reserve().Delay(); d > time.Second {
+		t.Fatalf("Delay after canceled Wait = %v, want at most 1s", d)
+	}
+}
+
+func TestLimiterInf(t *testing.T) {
+	l := NewLimiter(Inf, 0)
+	if !l.AllowN(1 << 20) {
+		t.Fatal("Inf limiter refused events")
+	}
+	if err := l.WaitN(context.Background(), 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if d := l.ReserveN(1 << 20).Delay(); d != 0 {
+		t.Fatalf("Inf reservation delay = %v", d)
+	}
+}
+
+// chunkWriter 记录每次写入的大小
+type chunkWriter struct {
+	bytes.Buffer
+	chunks []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestRateWriterChunking(t *testing.T) {
+	w := &chunkWriter{}
+	rw, err := NewRateWriter(context.Background(), w, NewLimiter(1e6, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := rw.Write([]byte("0123456789"))
+	if n != 10 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if w.String() != "0123456789" || len(w.chunks) != 3 || w.chunks[0] != 4 || w.chunks[2] != 2 {
+		t.Fatalf("wrote %q in chunks %v, want chunks of at most 4", w.String(), w.chunks)
+	}
+
+	// 不限速时不拆分
+	w = &chunkWriter{}
+	rw, _ = NewRateWriter(context.Background(), w, NewLimiter(Inf, 0))
+	_, _ = rw.Write([]byte("0123456789"))
+	if len(w.chunks) != 1 {
+		t.Fatalf("Inf limiter split the write into %v", w.chunks)
+	}
+}
+
+func TestRateReaderChunking(t *testing.T) {
+	rr, err := NewRateReader(context.Background(), bytes.NewReader([]byte("0123456789")), NewLimiter(1e6, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	if n, err := rr.Read(buf); n != 4 || err != nil {
+		t.Fatalf("Read = %d, %v, want 4 bytes", n, err)
+	}
+	rest, err := io.ReadAll(rr)
+	if err != nil || string(rest) != "456789" {
+		t.Fatalf("ReadAll = %q, %v", rest, err)
+	}
+}
+
+func TestRateIOZeroBurst(t *testing.T) {
+	if _, err := NewRateWriter(context.Background(), io.Discard, NewLimiter(1, 0)); err != ErrZeroBurst {
+		t.Fatalf("NewRateWriter = %v, want ErrZeroBurst", err)
+	}
+	if _, err := NewRateReader(context.Background(), bytes.NewReader(nil), NewLimiter(1, 0)); err != ErrZeroBurst {
+		t.Fatalf("NewRateReader = %v, want ErrZeroBurst", err)
+	}
+
+	l := NewLimiter(1e6, 4)
+	rw, _ := NewRateWriter(context.Background(), io.Discard, l)
+	l.SetBurst(0)
+	if _, err := rw.Write([]byte("x")); err != ErrZeroBurst {
+		t.Fatalf("Write after SetBurst(0) = %v, want ErrZeroBurst", err)
+	}
+}
@@ -0,0 +1,128 @@
+package singleflight
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PanicError fn panic 时其他等待同一个key的调用方得到的错误
+type PanicError struct {
+	Value interface{}
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: fn panicked: %v", p.Value)
+}
+
+// 正在执行或者已经执行完的调用
+type call struct {
+	// 执行结束时close
+	done chan struct{}
+	val  interface{}
+	err  error
+	// 等待这次调用结果的其他调用方数量
+	dups  int
+	chans []chan<- Result
+}
+
+// Result DoChan 返回的结果
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool
+}
+
+// Group 合并相同key的并发调用，同一时间每个key只执行一次 fn
+type Group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+// Do 执行 fn 并返回结果，相同key正在执行时等待那次执行的结果
+// shared 表示结果是否同时返回给了多个调用方
+// fn panic 时执行它的调用方会继续 panic，其他调用方得到 *PanicError
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.val, c.err, true
+	}
+	c := &call{done: make(chan struct{})}
+	g.m[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan 和 Do 一样，但是不阻塞，结果从返回的通道中读取
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+	c := &call{done: make(chan struct{}), chans: []chan<- Result{ch}}
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			// DoChan 的调用方已经拿到了 PanicError，这里不再继续 panic
+			_ = recover()
+		}()
+		g.doCall(c, key, fn)
+	}()
+	return ch
+}
+
+// Forget 忘掉正在执行的key，之后相同key的调用会重新执行 fn 而不是等待
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+}
+
+// doCall 执行 fn 并通知所有等待者
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
+	var recovered interface{}
+	defer func() {
+		g.mu.Lock()
+		// Forget 之后可能已经有新的调用使用了这个key
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		shared := c.dups > 0
+		chans := c.chans
+		g.mu.Unlock()
+
+		close(c.done)
+		for _, ch := range chans {
+			ch <- Result{Val: c.val, Err: c.err, Shared: shared}
+		}
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				recovered = r
+				c.err = &PanicError{Value: r}
+			}
+		}()
+		c.val, c.err = fn()
+	}()
+}
@@ -0,0 +1,179 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var g Group
+	errFailed := errors.New("failed")
+	v, err, shared := g.Do("key", func() (interface{}, error) {
+		return "bar", errFailed
+	})
+	if v != "bar" || err != errFailed || shared {
+		t.Fatalf("Do = %v, %v, %v", v, err, shared)
+	}
+}
+
+func TestDoDedup(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("key", fn)
+			if v != "bar" || err != nil || !shared {
+				t.Errorf("Do = %v, %v, %v", v, err, shared)
+			}
+		}()
+	}
+	// 等所有调用方都在等待同一次执行
+	waitDups(t, &g, "key", n-1)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("fn ran %d times, want 1", calls)
+	}
+}
+
+func TestDoChanDedup(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	first := g.DoChan("key", fn)
+	second := g.DoChan("key", fn)
+	close(release)
+	for _, ch := range []<-chan Result{first, second} {
+		if r := <-ch; r.Val != "bar" || r.Err != nil || !r.Shared {
+			t.Fatalf("DoChan result %+v", r)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("fn ran %d times, want 1", calls)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	waiterErr := make(chan error, 1)
+
+	go func() {
+		waitDups(t, &g, "key", 0)
+		go func() {
+			_, err, _ := g.Do("key", func() (interface{}, error) {
+				t.Error("fn ran twice")
+				return nil, nil
+			})
+			waiterErr <- err
+		}()
+		waitDups(t, &g, "key", 1)
+		close(release)
+	}()
+
+	// 执行 fn 的调用方继续 panic，其他调用方得到 PanicError
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v, want boom", r)
+			}
+		}()
+		_, _, _ = g.Do("key", func() (interface{}, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+
+	var pe *PanicError
+	if err := <-waiterErr; !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("waiter got %v, want PanicError(boom)", err)
+	}
+}
+
+func TestDoChanPanic(t *testing.T) {
+	var g Group
+	r := <-g.DoChan("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	var pe *PanicError
+	if !errors.As(r.Err, &pe) || pe.Value != "boom" {
+		t.Fatalf("DoChan err = %v, want PanicError(boom)", r.Err)
+	}
+}
+
+func TestForget(t *testing.T) {
+	var g Group
+	releaseFirst := make(chan struct{})
+	first := g.DoChan("key", func() (interface{}, error) {
+		<-releaseFirst
+		return 1, nil
+	})
+
+	// Forget 之后相同key重新执行 fn
+	g.Forget("key")
+	releaseSecond := make(chan struct{})
+	second := g.DoChan("key", func() (interface{}, error) {
+		<-releaseSecond
+		return 2, nil
+	})
+
+	close(releaseFirst)
+	if r := <-first; r.Val != 1 || r.Shared {
+		t.Fatalf("first call = %+v", r)
+	}
+	// 旧的执行结束时不能删除新调用使用的key
+	go func() {
+		waitDups(t, &g, "key", 1)
+		close(releaseSecond)
+	}()
+	v, _, shared := g.Do("key", func() (interface{}, error) {
+		t.Error("fn ran while the second call was in flight")
+		return 3, nil
+	})
+	if v != 2 || !shared {
+		t.Fatalf("Do = %v, %v, want the in-flight result 2", v, shared)
+	}
+	if r := <-second; r.Val != 2 {
+		t.Fatalf("second call = %+v", r)
+	}
+}
+
+// waitDups 等待 key 正在执行并且有 dups 个其他调用方在等待
+func waitDups(t *testing.T, g *Group, key string, dups int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		c, ok := g.m[key]
+		done := ok && c.dups >= dups
+		g.mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("timed out waiting for %d callers of %q", dups, key)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,38 +1,79 @@
 package sync
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
+// Wait 可以设置超时或者通过 context 取消等待的 WaitGroup
+// 用计数器加通道实现，等待超时不会留下阻塞的 goroutine
 type Wait struct {
-	wg sync.WaitGroup
+	mu sync.Mutex
+	// 还没有完成的任务数量
+	n int
+	// 计数器回到0时关闭，计数器为0时为nil
+	done chan struct{}
 }
 
 func (w *Wait) Add(delta int) {
-	w.wg.Add(delta)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.n == 0 && delta > 0 {
+		w.done = make(chan struct{})
+	}
+	w.n += delta
+	if w.n < 0 {
+		// 跟sync库里的WaitGroup一样，不允许计数器为负数
+		panic("sync: negative Wait counter")
+	}
+	if w.n == 0 && w.done != nil {
+		// 释放所有的等待者
+		close(w.done)
+		w.done = nil
+	}
 }
+
 func (w *Wait) Done() {
-	w.wg.Done()
+	w.Add(-1)
 }
 
 func (w *Wait) Wait() {
-	w.wg.Wait()
+	if done := w.doneChan(); done != nil {
+		<-done
+	}
 }
 
-// return isTimeout
-func (w *Wait) WaitWithTimeout(timeout time.Duration) bool {
-	c := make(chan bool)
-	go func() {
-		defer close(c)
-		w.wg.Wait() // 等待当前wait group结束
-		c <- true
-	}()
+// WaitContext 等待计数器回到0，ctx 结束时返回 ctx.Err()
+func (w *Wait) WaitContext(ctx context.Context) error {
+	done := w.doneChan()
+	if done == nil {
+		return nil
+	}
 	select {
-	// 要么wait group结束，要么时间到
-	case <-c:
-		return false
-	case <-time.After(timeout):
-		return true
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
+
+// return isTimeout
+func (w *Wait) WaitWithTimeout(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return w.WaitContext(ctx) != nil
+}
+
+// Count 返回还没有完成的任务数量，用于关闭时排查
+func (w *Wait) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
+
+func (w *Wait) doneChan() chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.done
+}
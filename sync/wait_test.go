@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	var w Wait
+	// 计数器为0时不阻塞
+	w.Wait()
+
+	w.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			time.Sleep(time.Millisecond)
+			w.Done()
+		}()
+	}
+	w.Wait()
+	if n := w.Count(); n != 0 {
+		t.Fatalf("Count = %d after Wait, want 0", n)
+	}
+}
+
+func TestWaitWithTimeout(t *testing.T) {
+	var w Wait
+	w.Add(1)
+	if !w.WaitWithTimeout(5 * time.Millisecond) {
+		t.Fatal("WaitWithTimeout should time out while the counter is 1")
+	}
+	w.Done()
+	if w.WaitWithTimeout(time.Second) {
+		t.Fatal("WaitWithTimeout timed out with a zero counter")
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	var w Wait
+	w.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.WaitContext(ctx); err != context.Canceled {
+		t.Fatalf("WaitContext = %v, want Canceled", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		w.Done()
+	}()
+	if err := w.WaitContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitReuse(t *testing.T) {
+	// 计数器回到0之后可以再次使用，之前超时的等待不影响下一轮
+	var w Wait
+	for round := 0; round < 3; round++ {
+		w.Add(2)
+		if !w.WaitWithTimeout(time.Millisecond) {
+			t.Fatalf("round %d: WaitWithTimeout should time out", round)
+		}
+		w.Done()
+		w.Done()
+		w.Wait()
+	}
+}
+
+func TestWaitNegative(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Done on a zero counter should panic")
+		}
+	}()
+	var w Wait
+	w.Done()
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"container/list"
+	"context"
+	"errors"
+)
+
+var ErrWeightTooLarge = errors.New("sync: acquire weight exceeds semaphore size")
+
+// Weighted 带权重的信号量，一次可以获取 n 个单位
+// 等待者按照先来后到的顺序获取，前面的大请求没有满足时后面的小请求也要等待，避免大请求饿死
+type Weighted struct {
+	size int64
+	cur  int64
+	// 用 channel 实现的互斥锁保护上面的计数和等待队列
+	mu      Mutex
+	waiters list.List
+}
+
+// 等待者
+type weightedWaiter struct {
+	n int64
+	// 获取成功时被close
+	ready chan struct{}
+}
+
+func NewWeighted(n int64) *Weighted {
+	return &Weighted{size: n, mu: NewMutex()}
+}
+
+// Acquire 获取 n 个单位，ctx 结束时放弃并返回 ctx.Err()
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrWeightTooLarge
+	}
+	// 没有人在等并且剩余的够用，直接获取
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(weightedWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// 放弃的同时已经获取成功了，当作获取成功
+			s.mu.Unlock()
+			return nil
+		default:
+		}
+		isFront := s.waiters.Front() == elem
+		s.waiters.Remove(elem)
+		// 排在队首的等待者离开了，后面的等待者可能已经可以获取了
+		if isFront && s.size > s.cur {
+			s.notifyWaiters()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire 尝试获取 n 个单位，不阻塞，返回是否获取成功
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release 释放 n 个单位
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("sync: released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// notifyWaiters 按顺序唤醒可以获取的等待者，调用方需要持有锁
+func (s *Weighted) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(weightedWaiter)
+		if s.size-s.cur < w.n {
+			// 队首的等待者还不能获取，为了公平后面的也不唤醒
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}